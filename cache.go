@@ -0,0 +1,187 @@
+package jsonschema
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type cacheKey struct {
+	t       reflect.Type
+	options string
+}
+
+type cacheEntry struct {
+	property *property
+	err      error
+}
+
+var typeCache = struct {
+	sync.Mutex
+	entries map[cacheKey]cacheEntry
+}{entries: make(map[cacheKey]cacheEntry)}
+
+// fieldKey identifies the schema of a struct field, which depends on its
+// tags as well as its type.
+type fieldKey struct {
+	t        reflect.Type
+	opts     tagOptions
+	schema   string
+	validate string
+}
+
+// ClearCache drops every schema stored for Documents using CacheTypes.
+func ClearCache() {
+	typeCache.Lock()
+	defer typeCache.Unlock()
+
+	typeCache.entries = make(map[cacheKey]cacheEntry)
+}
+
+// readCached returns a copy of the schema cached under key, restoring the
+// error it was generated with.
+func (d *Document) readCached(key cacheKey) (*property, bool) {
+	typeCache.Lock()
+	entry, ok := typeCache.entries[key]
+	typeCache.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	if entry.err != nil {
+		d.fail(entry.err)
+	}
+	return entry.property.clone(), true
+}
+
+func (d *Document) storeCached(key cacheKey) {
+	typeCache.Lock()
+	defer typeCache.Unlock()
+
+	typeCache.entries[key] = cacheEntry{property: d.property.clone(), err: d.err}
+}
+
+// cacheKey identifies the schema of t by the options that affect it. Every
+// exported option with a basic type is part of the key; Documents using
+// callbacks or type registrations aren't cached.
+func (d *Document) cacheKey(t reflect.Type) (cacheKey, bool) {
+	if !d.CacheTypes || d.hasRegistrations() {
+		return cacheKey{}, false
+	}
+
+	var options strings.Builder
+	v := reflect.ValueOf(d).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" || field.Anonymous || field.Name == "Schema" || field.Name == "Vocabulary" {
+			continue
+		}
+
+		value := v.Field(i)
+		switch value.Kind() {
+		case reflect.Bool:
+			options.WriteString(strconv.FormatBool(value.Bool()))
+		case reflect.String:
+			options.WriteString(strconv.Quote(value.String()))
+		case reflect.Int:
+			options.WriteString(strconv.FormatInt(value.Int(), 10))
+		default:
+			if !value.IsZero() {
+				return cacheKey{}, false
+			}
+		}
+		options.WriteByte(';')
+	}
+
+	return cacheKey{t: t, options: options.String()}, true
+}
+
+// readField reads the schema of a struct field from its type and tags. With
+// CacheTypes, fields sharing a type and tags reuse the schema read first
+// during the same read.
+func (d *Document) readField(field reflect.StructField, opts tagOptions) *property {
+	schemaTag := d.schemaTag(field)
+	key := fieldKey{t: field.Type, opts: opts, schema: schemaTag}
+	if d.UseValidatorTags {
+		key.validate = field.Tag.Get("validate")
+	}
+	if cached, ok := d.fields[key]; ok {
+		return cached.clone()
+	}
+
+	p := &property{}
+	p.read(d, field.Type, opts)
+	if d.UseValidatorTags {
+		p.readValidatorTag(d, field.Type, key.validate)
+	}
+	p.readSchemaTag(d, field.Type, schemaTag)
+
+	if d.CacheTypes {
+		if d.fields == nil {
+			d.fields = make(map[fieldKey]*property)
+		}
+		d.fields[key] = p.clone()
+	}
+
+	return p
+}
+
+// clone returns a deep copy of p, so that cached schemas can't be changed
+// through the Documents they are handed to.
+func (p *property) clone() *property {
+	if p == nil {
+		return nil
+	}
+
+	c := *p
+	c.Items = p.Items.clone()
+	c.Contains = p.Contains.clone()
+	c.PropertyNames = p.PropertyNames.clone()
+	c.AdditionalSchema = p.AdditionalSchema.clone()
+	c.TupleItems = cloneList(p.TupleItems)
+	c.Properties = cloneMap(p.Properties)
+	c.DependentSchemas = cloneMap(p.DependentSchemas)
+	c.AllOf = cloneList(p.AllOf)
+	c.AnyOf = cloneList(p.AnyOf)
+	c.OneOf = cloneList(p.OneOf)
+	c.If = p.If.clone()
+	c.Then = p.Then.clone()
+	c.Definitions = cloneMap(p.Definitions)
+	c.Required = append([]string(nil), p.Required...)
+	c.Enum = append([]interface{}(nil), p.Enum...)
+	c.Examples = append([]interface{}(nil), p.Examples...)
+	c.Order = append([]string(nil), p.Order...)
+	if p.Extensions != nil {
+		c.Extensions = make(map[string]interface{}, len(p.Extensions))
+		for key, value := range p.Extensions {
+			c.Extensions[key] = value
+		}
+	}
+
+	return &c
+}
+
+func cloneList(list []*property) []*property {
+	if list == nil {
+		return nil
+	}
+
+	c := make([]*property, len(list))
+	for i, p := range list {
+		c[i] = p.clone()
+	}
+	return c
+}
+
+func cloneMap(m map[string]*property) map[string]*property {
+	if m == nil {
+		return nil
+	}
+
+	c := make(map[string]*property, len(m))
+	for name, p := range m {
+		c[name] = p.clone()
+	}
+	return c
+}
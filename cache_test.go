@@ -0,0 +1,168 @@
+package jsonschema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type CacheUser struct {
+	Name    string
+	Email   string `json:",omitempty"`
+	Address struct {
+		Street string
+		Zip    string
+	}
+	Tags []string
+}
+
+func TestCacheTypes(t *testing.T) {
+	defer ClearCache()
+
+	uncached := &Document{}
+	uncached.Read(&CacheUser{})
+
+	first := &Document{CacheTypes: true}
+	first.Read(&CacheUser{})
+	second := &Document{CacheTypes: true}
+	second.Read(&CacheUser{})
+
+	if diff := cmp.Diff(uncached.property, second.property); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("copies are independent", func(t *testing.T) {
+		second.Properties["Address"].Properties["Zip"].Type = "integer"
+		second.Required[0] = "changed"
+
+		third := &Document{CacheTypes: true}
+		third.Read(&CacheUser{})
+		if diff := cmp.Diff(uncached.property, third.property); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("options are part of the key", func(t *testing.T) {
+		optional := &Document{CacheTypes: true, AllOptional: true}
+		optional.Read(&CacheUser{})
+		if optional.Required != nil {
+			t.Errorf("expected no required fields, got %v", optional.Required)
+		}
+	})
+	t.Run("overrides are applied to the copy", func(t *testing.T) {
+		overridden := &Document{CacheTypes: true}
+		overridden.Override("Name", &property{Type: "string", Format: "name"})
+		overridden.Read(&CacheUser{})
+		if overridden.Properties["Name"].Format != "name" {
+			t.Errorf("override not applied: %+v", overridden.Properties["Name"])
+		}
+
+		plain := &Document{CacheTypes: true}
+		plain.Read(&CacheUser{})
+		if plain.Properties["Name"].Format != "" {
+			t.Errorf("override leaked into cache: %+v", plain.Properties["Name"])
+		}
+	})
+	t.Run("errors are cached", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			d := &Document{CacheTypes: true}
+			if err := d.TryRead(&ErrorRoot{}); err == nil {
+				t.Errorf("read %d: expected an error", i)
+			}
+		}
+	})
+	t.Run("the schema isn't cached", func(t *testing.T) {
+		type Strict struct{ Name string }
+		unknown := &Document{Schema: "https://example.com/schema", Strict: true, CacheTypes: true}
+		if err := unknown.TryRead(&Strict{}); !errors.Is(err, ErrUnknownSchema) {
+			t.Errorf("expected ErrUnknownSchema, got %v", err)
+		}
+
+		known := &Document{Strict: true, CacheTypes: true}
+		if err := known.TryRead(&Strict{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := unknown.TryRead(&Strict{}); !errors.Is(err, ErrUnknownSchema) {
+			t.Errorf("expected ErrUnknownSchema from the cache, got %v", err)
+		}
+	})
+	t.Run("previous contents aren't cached", func(t *testing.T) {
+		ClearCache()
+		reused := &Document{CacheTypes: true}
+		reused.Title = "Previous"
+		reused.Read(&CacheUser{})
+
+		fresh := &Document{CacheTypes: true}
+		fresh.Read(&CacheUser{})
+		if diff := cmp.Diff(uncached.property, fresh.property); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("uncacheable configurations", func(t *testing.T) {
+		d := &Document{CacheTypes: true, IsRequired: func(reflect.StructField, TagOptions) bool { return true }}
+		if _, ok := d.cacheKey(reflect.TypeOf(CacheUser{})); ok {
+			t.Error("expected a Document with a callback to bypass the cache")
+		}
+	})
+}
+
+type CacheFieldTags struct {
+	Low       int `jsonschema:"default=1,enum=1|2"`
+	High      int `jsonschema:"default=9,enum=8|9"`
+	Plain     int
+	Optional  int `json:",omitempty"`
+	Alternate int `jsonschema:"default=1,enum=1|2"`
+}
+
+func TestCacheFieldsByTags(t *testing.T) {
+	defer ClearCache()
+
+	expected := map[string]*property{
+		"Low":       {Type: "integer", Default: int64(1), Enum: []interface{}{int64(1), int64(2)}},
+		"High":      {Type: "integer", Default: int64(9), Enum: []interface{}{int64(8), int64(9)}},
+		"Plain":     {Type: "integer"},
+		"Optional":  {Type: "integer"},
+		"Alternate": {Type: "integer", Default: int64(1), Enum: []interface{}{int64(1), int64(2)}},
+	}
+
+	j := &Document{CacheTypes: true}
+	j.Read(&CacheFieldTags{})
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff([]string{"Low", "High", "Plain", "Alternate"}, j.Required); diff != "" {
+		t.Error(diff)
+	}
+
+	j.Properties["Low"].Enum[0] = int64(0)
+	if j.Properties["Alternate"].Enum[0] != int64(1) {
+		t.Error("fields with the same tags must not share their schema")
+	}
+	if j.fields != nil {
+		t.Error("the field cache must not outlive the read")
+	}
+}
+
+type CacheBenchmark struct {
+	Basic   ExampleJSONBasic
+	Users   map[string]CacheUser
+	Slices  ExampleJSONNestedSlices
+	Formats ExampleJSONBuiltinFormats
+}
+
+func BenchmarkRead(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		d := &Document{}
+		d.Read(&CacheBenchmark{})
+	}
+}
+
+func BenchmarkReadCached(b *testing.B) {
+	defer ClearCache()
+
+	for i := 0; i < b.N; i++ {
+		d := &Document{CacheTypes: true}
+		d.Read(&CacheBenchmark{})
+	}
+}
@@ -0,0 +1,276 @@
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	definitionsPrefix = "#/definitions/"
+	defsPrefix        = "#/$defs/"
+)
+
+// readDefinition reads the named struct type t into the root definitions,
+// once per type, and makes p a reference to it.
+func (p *property) readDefinition(d *Document, t reflect.Type) {
+	name, seen := d.definitionName(t)
+	p.Ref = d.refPrefix() + name
+	p.Type = ""
+	if seen {
+		return
+	}
+
+	if d.Definitions == nil {
+		d.Definitions = make(map[string]*property)
+	}
+	definition := &property{}
+	d.Definitions[name] = definition
+
+	parent := d.path
+	d.path = ""
+	definition.readFromStruct(d, t)
+	d.path = parent
+}
+
+// Define reads the type of v into the root definitions under name, for
+// assembling a library of schemas in a single Document. With
+// UseDefinitions, references to the type from other definitions use name
+// as well, within the same Document. The definitions are kept by later
+// reads, next to those of the type read. It returns the first problem
+// found while reading, like TryRead.
+func (d *Document) Define(name string, v interface{}) error {
+	d.err = nil
+	d.setDefaultSchema()
+
+	t := derefType(reflect.TypeOf(v))
+	if d.defNames == nil {
+		d.defNames = make(map[reflect.Type]string)
+	}
+	d.defNames[t] = name
+	if d.Definitions == nil {
+		d.Definitions = make(map[string]*property)
+	}
+	existing := make(map[string]bool, len(d.Definitions))
+	for key := range d.Definitions {
+		existing[key] = key != name
+	}
+	definition := &property{}
+	d.Definitions[name] = definition
+
+	parent := d.path
+	d.path = joinPath("definitions", name)
+	d.fields = nil
+	// A struct is read in place, as read would make it a reference to
+	// itself with UseDefinitions.
+	if _, _, kind := d.getTypeFromMapping(t); kind == reflect.Struct && !d.isRegistered(t) {
+		definition.readFromStruct(d, t)
+	} else {
+		definition.read(d, t, "")
+	}
+	d.fields = nil
+	d.path = parent
+
+	// The definitions added for the types referenced by t are kept along
+	// with it.
+	if d.defined == nil {
+		d.defined = make(map[string]*property)
+	}
+	for _, key := range sortedKeys(d.Definitions) {
+		if !existing[key] {
+			d.finish(joinPath("definitions", key), d.Definitions[key])
+			d.defined[key] = d.Definitions[key]
+		}
+	}
+	if d.definedAs == nil {
+		d.definedAs = make(map[reflect.Type]string)
+	}
+	for typ, key := range d.defNames {
+		if _, ok := d.defined[key]; ok {
+			d.definedAs[typ] = key
+		}
+	}
+
+	return d.err
+}
+
+// definedTypeNames returns a copy of the definition names of the types read
+// by Define, for the references of a read to use.
+func (d *Document) definedTypeNames() map[reflect.Type]string {
+	if len(d.definedAs) == 0 {
+		return nil
+	}
+
+	names := make(map[reflect.Type]string, len(d.definedAs))
+	for t, name := range d.definedAs {
+		names[t] = name
+	}
+
+	return names
+}
+
+// addDefined adds the definitions of Define to those just read, unless the
+// read has a definition of the same name, which its references point to.
+func (d *Document) addDefined() {
+	for name, definition := range d.defined {
+		if d.Definitions == nil {
+			d.Definitions = make(map[string]*property)
+		}
+		if _, ok := d.Definitions[name]; !ok {
+			d.Definitions[name] = definition.clone()
+		}
+	}
+}
+
+// refPrefix returns the prefix of references to definitions.
+func (d *Document) refPrefix() string {
+	if d.RefPrefix == "" {
+		return definitionsPrefix
+	}
+
+	return d.RefPrefix
+}
+
+// definitionsPath returns the keys of the objects the definitions are
+// nested in according to a custom RefPrefix, e.g. "components" and
+// "schemas" for "#/components/schemas/", or nil for the default.
+func (d *Document) definitionsPath() []string {
+	prefix := d.refPrefix()
+	if prefix == definitionsPrefix || !strings.HasPrefix(prefix, "#/") {
+		return nil
+	}
+
+	return strings.Split(strings.Trim(strings.TrimPrefix(prefix, "#"), "/"), "/")
+}
+
+// dateTimeDefinition is the preferred name of the definition shared by
+// time.Time values with SharedDateTime.
+const dateTimeDefinition = "DateTime"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// readDateTimeDefinition makes p a reference to the definition shared by
+// all time.Time values, adding it on first use.
+func (p *property) readDateTimeDefinition(d *Document) {
+	name, seen := d.definitionNameAs(timeType, dateTimeDefinition)
+	p.Ref = d.refPrefix() + name
+	if seen {
+		return
+	}
+
+	if d.Definitions == nil {
+		d.Definitions = make(map[string]*property)
+	}
+	jsType, format, _ := d.getTypeFromMapping(timeType)
+	d.Definitions[name] = &property{Type: jsType, Format: format}
+}
+
+// definitionName returns the definition name of t and whether t was seen
+// before. Types sharing a name across packages are told apart by their
+// package name.
+func (d *Document) definitionName(t reflect.Type) (string, bool) {
+	return d.definitionNameAs(t, t.Name())
+}
+
+// definitionNameAs is like definitionName, preferring name for t.
+func (d *Document) definitionNameAs(t reflect.Type, name string) (string, bool) {
+	if name, ok := d.defNames[t]; ok {
+		return name, true
+	}
+	if d.defNames == nil {
+		d.defNames = make(map[reflect.Type]string)
+	}
+
+	for _, taken := range d.defNames {
+		if taken == name {
+			name = t.String()
+			break
+		}
+	}
+	d.defNames[t] = name
+
+	return name, false
+}
+
+// inlineSingleUse replaces every reference to a definition that is
+// referenced exactly once with the definition itself.
+func (d *Document) inlineSingleUse() {
+	counts := make(map[string]int)
+	d.property.forEach(func(p *property) {
+		if p.Ref != "" {
+			counts[p.Ref]++
+		}
+	})
+
+	inlined := make(map[string]bool)
+	var inline func(p *property)
+	inline = func(p *property) {
+		if p.Ref != "" && counts[p.Ref] == 1 {
+			name := strings.TrimPrefix(p.Ref, d.refPrefix())
+			if definition, ok := d.Definitions[name]; ok && !inlined[name] {
+				inlined[name] = true
+				site := *p
+				*p = *definition
+				overlay(p, &site)
+			}
+		}
+		p.forEachChild(inline)
+	}
+
+	inline(&d.property)
+	for name := range inlined {
+		delete(d.Definitions, name)
+	}
+	if len(d.Definitions) == 0 {
+		d.Definitions = nil
+	}
+}
+
+// overlay copies the keywords set on a reference site, such as a default
+// from a struct tag, onto the inlined definition.
+func overlay(p, site *property) {
+	dst := reflect.ValueOf(p).Elem()
+	src := reflect.ValueOf(site).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if src.Type().Field(i).Name == "Ref" || src.Field(i).IsZero() {
+			continue
+		}
+		dst.Field(i).Set(src.Field(i))
+	}
+}
+
+// Bundle returns the Document encoded as a single self-contained schema, in
+// which every "$ref" points into the definitions of the root. Dialects from
+// draft 2019-09 on keep definitions under "$defs" rather than
+// "definitions", so for those both the definitions and the references to
+// them are renamed, unless a custom RefPrefix is set. Like Marshal, it
+// returns the error that prevented encoding the schema.
+func (d *Document) Bundle() ([]byte, error) {
+	bundle := Document{Schema: d.Schema, Vocabulary: d.Vocabulary, RefPrefix: d.RefPrefix, property: *d.property.clone()}
+	if usesDefs(d.Schema) && bundle.Definitions != nil && bundle.refPrefix() == definitionsPrefix {
+		bundle.forEach(func(p *property) {
+			if strings.HasPrefix(p.Ref, definitionsPrefix) {
+				p.Ref = defsPrefix + strings.TrimPrefix(p.Ref, definitionsPrefix)
+			}
+		})
+		if bundle.Extensions == nil {
+			bundle.Extensions = make(map[string]interface{}, 1)
+		}
+		bundle.Extensions["$defs"] = bundle.Definitions
+		bundle.Definitions = nil
+	}
+
+	return bundle.Marshal()
+}
+
+// usesDefs reports whether the dialect of schema names the definitions
+// keyword "$defs".
+func usesDefs(schema string) bool {
+	schema, _ = NormalizeSchema(schema)
+	switch schema {
+	case Draft201909, Draft202012:
+		return true
+	}
+
+	return false
+}
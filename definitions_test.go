@@ -0,0 +1,485 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type DefinitionAddress struct {
+	Street string
+}
+
+type DefinitionContact struct {
+	Email string
+}
+
+type DefinitionNode struct {
+	Value    int
+	Children []DefinitionNode `json:",omitempty"`
+}
+
+type ExampleJSONDefinitions struct {
+	Home    DefinitionAddress
+	Work    *DefinitionAddress `json:",omitempty"`
+	Contact DefinitionContact  `jsonschema:"comment=primary contact"`
+	Tree    DefinitionNode
+}
+
+func TestUseDefinitions(t *testing.T) {
+	j := &Document{UseDefinitions: true}
+	j.Read(&ExampleJSONDefinitions{})
+
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Home":    {Ref: "#/definitions/DefinitionAddress"},
+			"Work":    {Ref: "#/definitions/DefinitionAddress"},
+			"Contact": {Ref: "#/definitions/DefinitionContact", Comment: "primary contact"},
+			"Tree":    {Ref: "#/definitions/DefinitionNode"},
+		},
+		Required: []string{"Home", "Contact", "Tree"},
+		Definitions: map[string]*property{
+			"DefinitionAddress": {
+				Type:       "object",
+				Properties: map[string]*property{"Street": {Type: "string"}},
+				Required:   []string{"Street"},
+			},
+			"DefinitionContact": {
+				Type:       "object",
+				Properties: map[string]*property{"Email": {Type: "string"}},
+				Required:   []string{"Email"},
+			},
+			"DefinitionNode": {
+				Type: "object",
+				Properties: map[string]*property{
+					"Value":    {Type: "integer"},
+					"Children": {Type: "array", Items: &property{Ref: "#/definitions/DefinitionNode"}},
+				},
+				Required: []string{"Value"},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestInlineSingleUse(t *testing.T) {
+	j := &Document{UseDefinitions: true, InlineSingleUse: true}
+	j.Read(&ExampleJSONDefinitions{})
+
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Home": {Ref: "#/definitions/DefinitionAddress"},
+			"Work": {Ref: "#/definitions/DefinitionAddress"},
+			"Contact": {
+				Type:       "object",
+				Properties: map[string]*property{"Email": {Type: "string"}},
+				Required:   []string{"Email"},
+				Comment:    "primary contact",
+			},
+			"Tree": {Ref: "#/definitions/DefinitionNode"},
+		},
+		Required: []string{"Home", "Contact", "Tree"},
+		Definitions: map[string]*property{
+			"DefinitionAddress": {
+				Type:       "object",
+				Properties: map[string]*property{"Street": {Type: "string"}},
+				Required:   []string{"Street"},
+			},
+			"DefinitionNode": {
+				Type: "object",
+				Properties: map[string]*property{
+					"Value":    {Type: "integer"},
+					"Children": {Type: "array", Items: &property{Ref: "#/definitions/DefinitionNode"}},
+				},
+				Required: []string{"Value"},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("all single use", func(t *testing.T) {
+		j := &Document{UseDefinitions: true, InlineSingleUse: true}
+		j.Read(&struct{ Address DefinitionAddress }{})
+
+		if j.Definitions != nil {
+			t.Errorf("expected no definitions, got %v", j.Definitions)
+		}
+		if j.Properties["Address"].Type != "object" {
+			t.Errorf("expected inlined object, got %+v", j.Properties["Address"])
+		}
+	})
+}
+
+func TestBundle(t *testing.T) {
+	tests := []struct {
+		schema  string
+		keyword string
+	}{
+		{schema: "http://json-schema.org/draft-07/schema#", keyword: "definitions"},
+		{schema: "https://json-schema.org/draft/2019-09/schema", keyword: "$defs"},
+		{schema: "https://json-schema.org/draft/2020-12/schema", keyword: "$defs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			j := NewDocument(tt.schema)
+			j.UseDefinitions = true
+			j.Read(&ExampleJSONDefinitions{})
+
+			var bundle map[string]interface{}
+			out, err := j.Bundle()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(out, &bundle); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := bundle[tt.keyword]; !ok {
+				t.Fatalf("expected definitions under %q, got %v", tt.keyword, bundle)
+			}
+
+			refs := collectRefs(bundle)
+			if len(refs) != 5 {
+				t.Errorf("expected 5 references, got %v", refs)
+			}
+			for _, ref := range refs {
+				if !strings.HasPrefix(ref, "#/"+tt.keyword+"/") {
+					t.Errorf("reference %q uses the wrong keyword", ref)
+				}
+				if resolvePointer(bundle, ref) == nil {
+					t.Errorf("reference %q doesn't resolve", ref)
+				}
+			}
+		})
+	}
+
+	t.Run("leaves the document unchanged", func(t *testing.T) {
+		j := NewDocument("https://json-schema.org/draft/2020-12/schema")
+		j.UseDefinitions = true
+		j.Read(&ExampleJSONDefinitions{})
+		if _, err := j.Bundle(); err != nil {
+			t.Fatal(err)
+		}
+
+		if j.Properties["Home"].Ref != "#/definitions/DefinitionAddress" || j.Definitions == nil {
+			t.Errorf("Bundle modified the document: %s", j)
+		}
+	})
+}
+
+// collectRefs returns every "$ref" in the decoded schema.
+func collectRefs(v interface{}) []string {
+	var refs []string
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				refs = append(refs, ref)
+			}
+			refs = append(refs, collectRefs(value)...)
+		}
+	case []interface{}:
+		for _, value := range v {
+			refs = append(refs, collectRefs(value)...)
+		}
+	}
+
+	return refs
+}
+
+// resolvePointer resolves a "#/..." JSON pointer within the decoded schema.
+func resolvePointer(root interface{}, ref string) interface{} {
+	current := root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[strings.NewReplacer("~1", "/", "~0", "~").Replace(token)]
+	}
+
+	return current
+}
+
+type DateTime struct {
+	Zone string
+}
+
+type ExampleJSONSharedDateTime struct {
+	Created time.Time
+	Updated *time.Time `json:",omitempty"`
+	History []time.Time
+	Local   DateTime
+}
+
+func TestSharedDateTime(t *testing.T) {
+	j := &Document{UseDefinitions: true, SharedDateTime: true}
+	j.Read(&ExampleJSONSharedDateTime{})
+
+	ref := &property{Ref: "#/definitions/DateTime"}
+	expected := map[string]*property{
+		"Created": ref,
+		"Updated": ref,
+		"History": {Type: "array", Items: ref},
+		"Local":   {Ref: "#/definitions/jsonschema.DateTime"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(&property{Type: "string", Format: "date-time"}, j.Definitions["DateTime"]); diff != "" {
+		t.Error(diff)
+	}
+	if len(j.Definitions) != 2 {
+		t.Errorf("expected two definitions, got %v", j.Definitions)
+	}
+
+	t.Run("requires definitions", func(t *testing.T) {
+		j := &Document{SharedDateTime: true}
+		j.Read(&ExampleJSONSharedDateTime{})
+
+		if diff := cmp.Diff(&property{Type: "string", Format: "date-time"}, j.Properties["Created"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+func TestRefPrefix(t *testing.T) {
+	j := &Document{UseDefinitions: true, RefPrefix: "#/components/schemas/"}
+	j.Read(&ExampleJSONDefinitions{})
+
+	if diff := cmp.Diff(&property{Ref: "#/components/schemas/DefinitionAddress"}, j.Properties["Home"]); diff != "" {
+		t.Error(diff)
+	}
+	if _, ok := j.Definitions["DefinitionAddress"]; !ok {
+		t.Errorf("expected the definition to be kept, got %v", j.Definitions)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(j.String()), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := schema["definitions"]; ok {
+		t.Error("definitions must be nested under components")
+	}
+	refs := collectRefs(schema)
+	if len(refs) != 5 {
+		t.Errorf("expected 5 references, got %v", refs)
+	}
+	for _, ref := range refs {
+		if resolvePointer(schema, ref) == nil {
+			t.Errorf("reference %q doesn't resolve", ref)
+		}
+	}
+
+	t.Run("bundle keeps the prefix", func(t *testing.T) {
+		j.Schema = "https://json-schema.org/draft/2020-12/schema"
+
+		var bundle map[string]interface{}
+		out, err := j.Bundle()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(out, &bundle); err != nil {
+			t.Fatal(err)
+		}
+		if resolvePointer(bundle, "#/components/schemas/DefinitionAddress") == nil {
+			t.Errorf("unexpected bundle %v", bundle)
+		}
+	})
+	t.Run("inline single use", func(t *testing.T) {
+		j := &Document{UseDefinitions: true, InlineSingleUse: true, RefPrefix: "#/components/schemas/"}
+		j.Read(&ExampleJSONDefinitions{})
+
+		if j.Properties["Contact"].Ref != "" || j.Definitions["DefinitionContact"] != nil {
+			t.Errorf("expected DefinitionContact to be inlined, got %+v", j.Properties["Contact"])
+		}
+	})
+}
+
+type EmbedBase struct {
+	ID      string `json:"id"`
+	Created string `json:"created,omitempty"`
+}
+
+type ExampleJSONEmbedAsAllOf struct {
+	EmbedBase
+	Name string `json:"name"`
+}
+
+func TestEmbedAsAllOf(t *testing.T) {
+	flattened := &Document{}
+	flattened.Read(&ExampleJSONEmbedAsAllOf{})
+
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"id":      {Type: "string"},
+			"created": {Type: "string"},
+			"name":    {Type: "string"},
+		},
+		Required: []string{"id", "name"},
+	}
+	if diff := cmp.Diff(expected, flattened.property); diff != "" {
+		t.Error(diff)
+	}
+
+	j := &Document{EmbedAsAllOf: true}
+	j.Read(&ExampleJSONEmbedAsAllOf{})
+
+	expected = property{
+		Type: "object",
+		AllOf: []*property{
+			{Ref: "#/definitions/EmbedBase"},
+			{
+				Type:       "object",
+				Properties: map[string]*property{"name": {Type: "string"}},
+				Required:   []string{"name"},
+			},
+		},
+		Definitions: map[string]*property{
+			"EmbedBase": {
+				Type: "object",
+				Properties: map[string]*property{
+					"id":      {Type: "string"},
+					"created": {Type: "string"},
+				},
+				Required: []string{"id"},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("nested in definitions", func(t *testing.T) {
+		j := &Document{EmbedAsAllOf: true, UseDefinitions: true}
+		j.Read(&struct {
+			Items []ExampleJSONEmbedAsAllOf
+		}{})
+
+		definition := j.Definitions["ExampleJSONEmbedAsAllOf"]
+		if definition == nil || len(definition.AllOf) != 2 || definition.AllOf[0].Ref != "#/definitions/EmbedBase" {
+			t.Errorf("unexpected definition %+v", definition)
+		}
+	})
+}
+
+type DefinedOrder struct {
+	ID      int
+	Contact DefinitionContact
+	Ship    *DefinitionAddress `json:",omitempty"`
+}
+
+func TestDefine(t *testing.T) {
+	j := NewDocument("https://json-schema.org/draft/2020-12/schema")
+	j.UseDefinitions = true
+	for name, v := range map[string]interface{}{"Address": DefinitionAddress{}, "Contact": &DefinitionContact{}} {
+		if err := j.Define(name, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Define("Order", DefinedOrder{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]*property{
+		"Address": {
+			Type:       "object",
+			Properties: map[string]*property{"Street": {Type: "string"}},
+			Required:   []string{"Street"},
+		},
+		"Contact": {
+			Type:       "object",
+			Properties: map[string]*property{"Email": {Type: "string"}},
+			Required:   []string{"Email"},
+		},
+		"Order": {
+			Type: "object",
+			Properties: map[string]*property{
+				"ID":      {Type: "integer"},
+				"Contact": {Ref: "#/definitions/Contact"},
+				"Ship":    {Ref: "#/definitions/Address"},
+			},
+			Required: []string{"ID", "Contact"},
+		},
+	}
+	if diff := cmp.Diff(expected, j.Definitions); diff != "" {
+		t.Error(diff)
+	}
+	if j.Type != "" || j.Properties != nil {
+		t.Errorf("unexpected root schema: %s", j)
+	}
+
+	var bundle map[string]interface{}
+	out, err := j.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range collectRefs(bundle) {
+		if !strings.HasPrefix(ref, "#/$defs/") || resolvePointer(bundle, ref) == nil {
+			t.Errorf("reference %q doesn't resolve", ref)
+		}
+	}
+}
+
+type DefinedOptions struct {
+	Website string `jsonschema:"format=uri,examples=https://example.com"`
+	Level   int    `jsonschema:"enum=1|2"`
+}
+
+func TestDefineOptions(t *testing.T) {
+	j := &Document{DisableFormats: true, StringEnums: true, SingleExample: true}
+	if err := j.Define("Options", DefinedOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Website": {Type: "string", Example: "https://example.com"},
+			"Level":   {Type: "integer", Enum: []interface{}{"1", "2"}},
+		},
+		Required: []string{"Website", "Level"},
+	}
+	if diff := cmp.Diff(expected, j.Definitions["Options"]); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDefineThenRead(t *testing.T) {
+	defer ClearCache()
+
+	for i := 0; i < 2; i++ {
+		j := &Document{CacheTypes: true, UseDefinitions: true}
+		if err := j.Define("Address", DefinitionAddress{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := j.TryRead(&DefinedOrder{}); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"Address", "DefinitionContact"}, sortedKeys(j.Definitions)); diff != "" {
+			t.Errorf("read %d: %s", i, diff)
+		}
+		if ref := j.Properties["Ship"].Ref; ref != "#/definitions/Address" {
+			t.Errorf("read %d: expected a reference to the defined name, got %q", i, ref)
+		}
+	}
+
+	plain := &Document{CacheTypes: true, UseDefinitions: true}
+	plain.Read(&DefinedOrder{})
+	if _, ok := plain.Definitions["Address"]; ok {
+		t.Error("a definition of Define leaked into the cache")
+	}
+}
@@ -0,0 +1,68 @@
+package jsonschema
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnknownSchema is reported in Strict mode when the Document's $schema is
+// not a known JSON Schema dialect URL.
+var ErrUnknownSchema = errors.New("jsonschema: unknown $schema")
+
+// The canonical $schema URLs of the recognized dialects, for use with
+// NewDocument.
+const (
+	Draft04     = "http://json-schema.org/draft-04/schema#"
+	Draft06     = "http://json-schema.org/draft-06/schema#"
+	Draft07     = "http://json-schema.org/draft-07/schema#"
+	Draft201909 = "https://json-schema.org/draft/2019-09/schema"
+	Draft202012 = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// knownSchemas holds the canonical URL of every recognized dialect.
+var knownSchemas = []string{
+	defaultSchema,
+	Draft04,
+	Draft06,
+	Draft07,
+	Draft201909,
+	Draft202012,
+}
+
+// NormalizeSchema returns the canonical form of a known dialect URL,
+// ignoring the scheme and any trailing "#" or "/", and whether the URL was
+// recognized. Unknown URLs are returned unchanged.
+func NormalizeSchema(schema string) (string, bool) {
+	key := schemaKey(schema)
+	for _, known := range knownSchemas {
+		if schemaKey(known) == key {
+			return known, true
+		}
+	}
+
+	return schema, false
+}
+
+func schemaKey(schema string) string {
+	key := strings.TrimSpace(schema)
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	key = strings.TrimRight(key, "#")
+	key = strings.TrimSuffix(key, "/")
+
+	return key
+}
+
+// StandardVocabulary returns the vocabularies of the draft 2020-12
+// meta-schema, all required, for use as Document.Vocabulary.
+func StandardVocabulary() map[string]bool {
+	return map[string]bool{
+		"https://json-schema.org/draft/2020-12/vocab/core":              true,
+		"https://json-schema.org/draft/2020-12/vocab/applicator":        true,
+		"https://json-schema.org/draft/2020-12/vocab/unevaluated":       true,
+		"https://json-schema.org/draft/2020-12/vocab/validation":        true,
+		"https://json-schema.org/draft/2020-12/vocab/meta-data":         true,
+		"https://json-schema.org/draft/2020-12/vocab/format-annotation": true,
+		"https://json-schema.org/draft/2020-12/vocab/content":           true,
+	}
+}
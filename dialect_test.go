@@ -0,0 +1,160 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestNormalizeSchema(t *testing.T) {
+	tests := []struct {
+		schema   string
+		expected string
+		known    bool
+	}{
+		{"https://json-schema.org/draft-07/schema", "http://json-schema.org/draft-07/schema#", true},
+		{"http://json-schema.org/draft-07/schema#", "http://json-schema.org/draft-07/schema#", true},
+		{"http://json-schema.org/draft-04/schema", "http://json-schema.org/draft-04/schema#", true},
+		{"http://json-schema.org/draft/2020-12/schema#", "https://json-schema.org/draft/2020-12/schema", true},
+		{"https://json-schema.org/draft/2019-09/schema/", "https://json-schema.org/draft/2019-09/schema", true},
+		{"https://json-schema.org/schema", "http://json-schema.org/schema#", true},
+		{"https://example.com/my-schema", "https://example.com/my-schema", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			schema, known := NormalizeSchema(tt.schema)
+			if schema != tt.expected || known != tt.known {
+				t.Errorf("got (%q, %v), expected (%q, %v)", schema, known, tt.expected, tt.known)
+			}
+		})
+	}
+}
+
+func TestNewDocumentNormalizesSchema(t *testing.T) {
+	d := NewDocument("https://json-schema.org/draft-07/schema")
+	if d.Schema != "http://json-schema.org/draft-07/schema#" {
+		t.Errorf("unexpected schema %q", d.Schema)
+	}
+}
+
+func TestDialectConstants(t *testing.T) {
+	tests := []struct {
+		schema      string
+		definitions string
+		contains    bool
+		vocabulary  bool
+	}{
+		{Draft04, "definitions", false, false},
+		{Draft06, "definitions", true, false},
+		{Draft07, "definitions", true, false},
+		{Draft201909, "$defs", true, false},
+		{Draft202012, "$defs", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			d := NewDocument(tt.schema)
+			if d.Schema != tt.schema {
+				t.Errorf("constant isn't canonical: %q", d.Schema)
+			}
+			d.UseDefinitions = true
+			d.Vocabulary = StandardVocabulary()
+			d.SetContains("Items", &property{Type: "string"})
+			err := d.TryRead(&struct {
+				Items []string
+				Home  DefinitionAddress
+			}{})
+			if (err == nil) != tt.contains {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			var bundle map[string]interface{}
+			out, err := d.Bundle()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(out, &bundle); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := bundle[tt.definitions]; !ok {
+				t.Errorf("expected %q, got %v", tt.definitions, bundle)
+			}
+			if _, ok := bundle["$vocabulary"]; ok != tt.vocabulary {
+				t.Errorf("unexpected $vocabulary in %v", bundle)
+			}
+		})
+	}
+}
+
+func TestUnknownSchema(t *testing.T) {
+	t.Run("lenient", func(t *testing.T) {
+		d := NewDocument("https://example.com/my-schema")
+		if err := d.TryRead(true); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if d.Schema != "https://example.com/my-schema" {
+			t.Errorf("unexpected schema %q", d.Schema)
+		}
+	})
+	t.Run("strict", func(t *testing.T) {
+		d := &Document{Schema: "https://example.com/my-schema", Strict: true}
+		if err := d.TryReadDeep(true); !errors.Is(err, ErrUnknownSchema) {
+			t.Errorf("expected ErrUnknownSchema, got %v", err)
+		}
+	})
+	t.Run("strict known", func(t *testing.T) {
+		d := &Document{Schema: "https://json-schema.org/draft-07/schema", Strict: true}
+		if err := d.TryRead(true); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if d.Schema != "http://json-schema.org/draft-07/schema#" {
+			t.Errorf("unexpected schema %q", d.Schema)
+		}
+	})
+}
+
+func TestVocabulary(t *testing.T) {
+	j := NewDocument("https://json-schema.org/draft/2020-12/schema")
+	j.Vocabulary = map[string]bool{
+		"https://json-schema.org/draft/2020-12/vocab/core":       true,
+		"https://json-schema.org/draft/2020-12/vocab/validation": true,
+		"https://example.com/vocab/units":                        false,
+	}
+	j.Read(true)
+
+	out, err := j.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema",` +
+		`"$vocabulary":{"https://example.com/vocab/units":false,"https://json-schema.org/draft/2020-12/vocab/core":true,"https://json-schema.org/draft/2020-12/vocab/validation":true},` +
+		`"type":"boolean"}`
+	if string(out) != expected {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	t.Run("other dialects", func(t *testing.T) {
+		j := NewDocument("http://json-schema.org/draft-07/schema#")
+		j.Vocabulary = StandardVocabulary()
+		j.Read(true)
+
+		out, err := j.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != `{"$schema":"http://json-schema.org/draft-07/schema#","type":"boolean"}` {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+	})
+	t.Run("standard vocabulary", func(t *testing.T) {
+		vocabulary := StandardVocabulary()
+		if len(vocabulary) != 7 || !vocabulary["https://json-schema.org/draft/2020-12/vocab/core"] {
+			t.Errorf("unexpected vocabulary %v", vocabulary)
+		}
+		vocabulary["https://json-schema.org/draft/2020-12/vocab/core"] = false
+		if !StandardVocabulary()["https://json-schema.org/draft/2020-12/vocab/core"] {
+			t.Error("StandardVocabulary must return a new map")
+		}
+	})
+}
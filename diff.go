@@ -0,0 +1,143 @@
+package jsonschema
+
+import (
+	"sort"
+	"strings"
+)
+
+// ChangeKind describes the kind of difference found between two schemas
+type ChangeKind string
+
+const (
+	PropertyAdded   ChangeKind = "added"
+	PropertyRemoved ChangeKind = "removed"
+	TypeChanged     ChangeKind = "type"
+	FormatChanged   ChangeKind = "format"
+	RefChanged      ChangeKind = "ref"
+	RequiredAdded   ChangeKind = "required-added"
+	RequiredRemoved ChangeKind = "required-removed"
+)
+
+// Change is a single structural difference between two Documents. Path is
+// the dotted path of the affected property, with "[]" denoting array items,
+// "additionalProperties" the schema of additional properties and
+// "definitions.Name" a definition; the root is the empty path. The type of
+// a nullable property is listed with "null", e.g. "string,null".
+type Change struct {
+	Path string
+	Kind ChangeKind
+	Old  string
+	New  string
+}
+
+// Diff compares two Documents structurally and returns the differences
+// needed to turn a into b, ordered by path. Changes to the same path keep
+// the order they are found in: the required list before the property.
+func Diff(a, b *Document) []Change {
+	if a == nil {
+		a = &Document{}
+	}
+	if b == nil {
+		b = &Document{}
+	}
+
+	var changes []Change
+	diffProperty("", &a.property, &b.property, &changes)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+func diffProperty(path string, a, b *property, changes *[]Change) {
+	if aType, bType := typeName(a), typeName(b); aType != bType {
+		*changes = append(*changes, Change{Path: path, Kind: TypeChanged, Old: aType, New: bType})
+	}
+	if a.Format != b.Format {
+		*changes = append(*changes, Change{Path: path, Kind: FormatChanged, Old: a.Format, New: b.Format})
+	}
+	if a.Ref != b.Ref {
+		*changes = append(*changes, Change{Path: path, Kind: RefChanged, Old: a.Ref, New: b.Ref})
+	}
+
+	diffRequired(path, a.Required, b.Required, changes)
+
+	for _, name := range unionKeys(a.Properties, b.Properties) {
+		diffChild(joinPath(path, name), a.Properties[name], b.Properties[name], changes)
+	}
+	diffChild(path+"[]", a.Items, b.Items, changes)
+	diffChild(joinPath(path, "additionalProperties"), a.AdditionalSchema, b.AdditionalSchema, changes)
+	for _, name := range unionKeys(a.Definitions, b.Definitions) {
+		diffChild(joinPath(joinPath(path, "definitions"), name), a.Definitions[name], b.Definitions[name], changes)
+	}
+}
+
+// diffChild compares the subschemas a and b, either of which may be missing.
+func diffChild(path string, a, b *property, changes *[]Change) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		*changes = append(*changes, Change{Path: path, Kind: PropertyAdded, New: typeName(b)})
+	case b == nil:
+		*changes = append(*changes, Change{Path: path, Kind: PropertyRemoved, Old: typeName(a)})
+	default:
+		diffProperty(path, a, b, changes)
+	}
+}
+
+// typeName returns the type of p as it is emitted, listing "null" after the
+// type of nullable properties.
+func typeName(p *property) string {
+	if typ, ok := p.encodedType().([]string); ok {
+		return strings.Join(typ, ",")
+	}
+
+	return p.Type
+}
+
+func diffRequired(path string, a, b []string, changes *[]Change) {
+	inA := make(map[string]bool, len(a))
+	for _, name := range a {
+		inA[name] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, name := range b {
+		inB[name] = true
+	}
+
+	for _, name := range a {
+		if !inB[name] {
+			*changes = append(*changes, Change{Path: joinPath(path, name), Kind: RequiredRemoved})
+		}
+	}
+	for _, name := range b {
+		if !inA[name] {
+			*changes = append(*changes, Change{Path: joinPath(path, name), Kind: RequiredAdded})
+		}
+	}
+}
+
+func unionKeys(a, b map[string]*property) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for name := range a {
+		keys = append(keys, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
@@ -0,0 +1,121 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type DiffUserV1 struct {
+	Name    string
+	Age     int
+	Email   string `json:",omitempty"`
+	Address struct {
+		Street string
+	}
+}
+
+type DiffUserV2 struct {
+	Name    string
+	Age     string
+	Email   string
+	Tags    []string
+	Address struct {
+		Street string
+		Zip    string
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Run("identical documents", func(t *testing.T) {
+		a := &Document{}
+		a.Read(&DiffUserV1{})
+		b := &Document{}
+		b.Read(&DiffUserV1{})
+
+		if changes := Diff(a, b); len(changes) != 0 {
+			t.Errorf("expected no changes, got %v", changes)
+		}
+	})
+	t.Run("added, removed and changed properties", func(t *testing.T) {
+		a := &Document{}
+		a.Read(&DiffUserV1{})
+		b := &Document{}
+		b.Read(&DiffUserV2{})
+
+		expected := []Change{
+			{Path: "Address.Zip", Kind: RequiredAdded},
+			{Path: "Address.Zip", Kind: PropertyAdded, New: "string"},
+			{Path: "Age", Kind: TypeChanged, Old: "integer", New: "string"},
+			{Path: "Email", Kind: RequiredAdded},
+			{Path: "Tags", Kind: RequiredAdded},
+			{Path: "Tags", Kind: PropertyAdded, New: "array"},
+		}
+		if diff := cmp.Diff(expected, Diff(a, b)); diff != "" {
+			t.Error(diff)
+		}
+
+		reverse := []Change{
+			{Path: "Address.Zip", Kind: RequiredRemoved},
+			{Path: "Address.Zip", Kind: PropertyRemoved, Old: "string"},
+			{Path: "Age", Kind: TypeChanged, Old: "string", New: "integer"},
+			{Path: "Email", Kind: RequiredRemoved},
+			{Path: "Tags", Kind: RequiredRemoved},
+			{Path: "Tags", Kind: PropertyRemoved, Old: "array"},
+		}
+		if diff := cmp.Diff(reverse, Diff(b, a)); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("array items and formats", func(t *testing.T) {
+		a := &Document{}
+		a.Read([]string{})
+		b := &Document{}
+		b.Read([]DiffUserV1{})
+
+		changes := Diff(a, b)
+		if len(changes) == 0 || changes[0].Path != "[]" || changes[0].Kind != TypeChanged {
+			t.Errorf("expected item type change, got %v", changes)
+		}
+	})
+	t.Run("definitions, references and nullability", func(t *testing.T) {
+		a := &Document{property: property{
+			Type: "object",
+			Properties: map[string]*property{
+				"Home":   {Ref: "#/definitions/Address"},
+				"Labels": {Type: "object", AdditionalSchema: &property{Type: "string"}},
+				"Note":   {Type: "string"},
+			},
+			Definitions: map[string]*property{"Address": {Type: "object"}},
+		}}
+		b := &Document{property: property{
+			Type: "object",
+			Properties: map[string]*property{
+				"Home":   {Ref: "#/definitions/Location"},
+				"Labels": {Type: "object", AdditionalSchema: &property{Type: "integer"}},
+				"Note":   {Type: "string", Nullable: true},
+			},
+			Definitions: map[string]*property{"Location": {Type: "object"}},
+		}}
+
+		expected := []Change{
+			{Path: "Home", Kind: RefChanged, Old: "#/definitions/Address", New: "#/definitions/Location"},
+			{Path: "Labels.additionalProperties", Kind: TypeChanged, Old: "string", New: "integer"},
+			{Path: "Note", Kind: TypeChanged, Old: "string", New: "string,null"},
+			{Path: "definitions.Address", Kind: PropertyRemoved, Old: "object"},
+			{Path: "definitions.Location", Kind: PropertyAdded, New: "object"},
+		}
+		if diff := cmp.Diff(expected, Diff(a, b)); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("nil documents", func(t *testing.T) {
+		b := &Document{}
+		b.Read(true)
+
+		expected := []Change{{Kind: TypeChanged, New: "boolean"}}
+		if diff := cmp.Diff(expected, Diff(nil, b)); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package jsonschema
+
+import (
+	"fmt"
+)
+
+// GenerationError describes a problem found while reading a Go type into a
+// Document. Path is the dotted path of the offending property, with "[]"
+// denoting array items, and is empty for the root. Type is the Go type of
+// the property, if known.
+type GenerationError struct {
+	Path   string
+	Type   string
+	Reason string
+}
+
+func (e *GenerationError) Error() string {
+	msg := "jsonschema: " + e.Reason
+	if e.Path != "" {
+		msg = fmt.Sprintf("jsonschema: %s: %s", e.Path, e.Reason)
+	}
+	if e.Type != "" {
+		msg += " (" + e.Type + ")"
+	}
+
+	return msg
+}
@@ -0,0 +1,100 @@
+package jsonschema
+
+import (
+	"errors"
+	"testing"
+)
+
+type ErrorLeaf struct {
+	Callback func() `json:"callback"`
+}
+
+type ErrorBranch struct {
+	Leaves []ErrorLeaf `json:"leaves"`
+}
+
+type ErrorRoot struct {
+	Name   string
+	Branch ErrorBranch `json:"branch"`
+}
+
+type ErrorBadTag struct {
+	Inner struct {
+		Retries int `jsonschema:"default=many"`
+	}
+}
+
+func TestGenerationError(t *testing.T) {
+	tests := []struct {
+		name     string
+		read     func(d *Document) error
+		expected GenerationError
+		message  string
+	}{
+		{
+			name:     "unsupported type in nested field",
+			read:     func(d *Document) error { return d.TryRead(&ErrorRoot{}) },
+			expected: GenerationError{Path: "branch.leaves[].callback", Type: "func()", Reason: "unsupported type"},
+			message:  "jsonschema: branch.leaves[].callback: unsupported type (func())",
+		},
+		{
+			name: "unsupported type in deep read",
+			read: func(d *Document) error {
+				return d.TryReadDeep(map[string]interface{}{"events": make(chan int)})
+			},
+			expected: GenerationError{Path: "events", Type: "chan int", Reason: "unsupported type"},
+		},
+		{
+			name:     "bad tag value",
+			read:     func(d *Document) error { return d.TryRead(&ErrorBadTag{}) },
+			expected: GenerationError{Path: "Inner.Retries", Type: "int", Reason: `invalid default value "many": invalid syntax`},
+		},
+		{
+			name:     "bad tag value in a definition",
+			read:     func(d *Document) error { return d.Define("Bad", ErrorBadTag{}) },
+			expected: GenerationError{Path: "definitions.Bad.Inner.Retries", Type: "int", Reason: `invalid default value "many": invalid syntax`},
+		},
+		{
+			name: "bad tag value in a union variant",
+			read: func(d *Document) error {
+				return d.DiscriminatedUnion("kind", map[string]interface{}{"bad": ErrorBadTag{}})
+			},
+			expected: GenerationError{Path: "kind=bad.Inner.Retries", Type: "int", Reason: `invalid default value "many": invalid syntax`},
+		},
+		{
+			name:     "unsupported root",
+			read:     func(d *Document) error { return d.TryRead(complex(1, 2)) },
+			expected: GenerationError{Type: "complex128", Reason: "unsupported type"},
+			message:  "jsonschema: unsupported type (complex128)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.read(&Document{})
+
+			var genErr *GenerationError
+			if !errors.As(err, &genErr) {
+				t.Fatalf("expected a GenerationError, got %v", err)
+			}
+			if *genErr != tt.expected {
+				t.Errorf("got %+v, expected %+v", *genErr, tt.expected)
+			}
+			if tt.message != "" && err.Error() != tt.message {
+				t.Errorf("unexpected message %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestGenerationErrorResetBetweenReads(t *testing.T) {
+	d := &Document{}
+	if err := d.TryRead(&ErrorRoot{}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	d.property = property{}
+	if err := d.TryRead(&ExampleJSONBasic{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package jsonschema
+
+import (
+	"strings"
+)
+
+// standardFormats lists the values of the "format" keyword defined by the
+// JSON Schema specification up to draft 2020-12.
+var standardFormats = map[string]bool{
+	"date-time":             true,
+	"date":                  true,
+	"time":                  true,
+	"duration":              true,
+	"email":                 true,
+	"idn-email":             true,
+	"hostname":              true,
+	"idn-hostname":          true,
+	"ipv4":                  true,
+	"ipv6":                  true,
+	"uri":                   true,
+	"uri-reference":         true,
+	"iri":                   true,
+	"iri-reference":         true,
+	"uri-template":          true,
+	"uuid":                  true,
+	"json-pointer":          true,
+	"relative-json-pointer": true,
+	"regex":                 true,
+}
+
+// DefaultFormatFallbacks holds basic patterns for common formats, for use
+// as FormatFallbacks. They check the shape of the values only, e.g. that an
+// email address has a single "@" and a dot in its domain.
+var DefaultFormatFallbacks = map[string]string{
+	"email":     `^[^@\s]+@[^@\s]+\.[^@\s]+$`,
+	"uuid":      `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`,
+	"date":      `^\d{4}-\d{2}-\d{2}$`,
+	"date-time": `^\d{4}-\d{2}-\d{2}[Tt]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})$`,
+	"ipv4":      `^(\d{1,3}\.){3}\d{1,3}$`,
+}
+
+// normalizeFormat lowercases a format given in a struct tag, since formats
+// are case sensitive, and reports whether it is a standard format.
+func normalizeFormat(format string) (string, bool) {
+	format = strings.ToLower(strings.TrimSpace(format))
+
+	return format, standardFormats[format]
+}
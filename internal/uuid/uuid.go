@@ -0,0 +1,24 @@
+// Package uuid stands in for third-party UUID packages in tests, which are
+// recognized by their package and type name.
+package uuid
+
+import "encoding/hex"
+
+type UUID [16]byte
+
+// MarshalText encodes the UUID as its hexadecimal string, as the packages
+// it stands in for do.
+func (u UUID) MarshalText() ([]byte, error) {
+	buf := make([]byte, 36)
+	hex.Encode(buf, u[:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+
+	return buf, nil
+}
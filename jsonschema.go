@@ -5,20 +5,155 @@ structures between different languages.
 package jsonschema
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"math"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 )
 
 var defaultSchema = "http://json-schema.org/schema#"
 
+// DefaultWriteOnlyPattern matches property names that usually hold secrets,
+// for use as Document.WriteOnlyPattern.
+var DefaultWriteOnlyPattern = regexp.MustCompile(`(?i)password|secret|token`)
+
 type Document struct {
 	Schema string `json:"$schema,omitempty"`
+	// Vocabulary declares the vocabularies the schema relies on, and
+	// whether each is required. It is emitted as "$vocabulary" for draft
+	// 2020-12 only; see StandardVocabulary.
+	Vocabulary map[string]bool `json:"$vocabulary,omitempty"`
 	property
+
+	// AllOptional omits the required list of every object, e.g. for PATCH
+	// request bodies where any field may be left out.
+	AllOptional bool `json:"-"`
+	// AllOpen allows additional properties on every struct, as SetOpen
+	// does for a single type.
+	AllOpen bool `json:"-"`
+	// Strict turns problems that are otherwise tolerated, such as an
+	// unknown $schema URL, into errors reported by TryRead and TryReadDeep.
+	Strict bool `json:"-"`
+	// IsRequired decides whether a struct field is listed as required. When
+	// nil, fields are required unless tagged with omitempty.
+	IsRequired func(field reflect.StructField, opts TagOptions) bool `json:"-"`
+	// DisableBuiltinFormats stops well-known types such as uuid.UUID and
+	// civil.Date from being emitted as formatted strings.
+	DisableBuiltinFormats bool `json:"-"`
+	// DisableFormats leaves out every "format", keeping the types, for
+	// validators that reject the formats they don't know.
+	DisableFormats bool `json:"-"`
+	// FormatFallbacks maps formats to the pattern emitted instead of them
+	// with DisableFormats, e.g. DefaultFormatFallbacks. Properties with a
+	// pattern of their own keep it.
+	FormatFallbacks map[string]string `json:"-"`
+	// StringerAsString emits struct types implementing fmt.Stringer as
+	// strings, for types whose custom marshalers encode them that way.
+	StringerAsString bool `json:"-"`
+	// UseDefinitions emits named struct types once under "definitions" and
+	// refers to them with "$ref", which also allows recursive types. It
+	// applies to Read; ReadDeep always inlines the values it inspects.
+	UseDefinitions bool `json:"-"`
+	// RefPrefix is the prefix of the references to definitions, e.g.
+	// "#/components/schemas/" for OpenAPI documents. The definitions are
+	// emitted at the matching location, and it defaults to
+	// "#/definitions/".
+	RefPrefix string `json:"-"`
+	// EmptyProperties emits "properties": {} for every object without
+	// properties, such as structs without fields and maps of any value, for
+	// tooling that requires the keyword. They are otherwise described by
+	// their type alone.
+	EmptyProperties bool `json:"-"`
+	// EmbedAsAllOf emits embedded structs as definitions combined with the
+	// fields of the embedding struct by "allOf", instead of promoting their
+	// fields. It applies to Read; ReadDeep always promotes.
+	EmbedAsAllOf bool `json:"-"`
+	// SharedDateTime makes UseDefinitions refer every time.Time to a
+	// single "DateTime" definition instead of inlining its format.
+	SharedDateTime bool `json:"-"`
+	// InlineSingleUse puts definitions that are referenced only once back
+	// in place of their reference.
+	InlineSingleUse bool `json:"-"`
+	// CacheTypes reuses the schema generated for a type by any Document
+	// with the same configuration, instead of reflecting it again. Within a
+	// read, struct fields with the same type and tags share their schema.
+	CacheTypes bool `json:"-"`
+	// OmitemptyAsNullable also accepts null for fields tagged with
+	// omitempty, emitting their type as e.g. ["string", "null"]. Fields
+	// without a type, such as references, are emitted as an anyOf of their
+	// schema and null.
+	OmitemptyAsNullable bool `json:"-"`
+	// JSONNumberAsString emits json.Number as a string instead of a
+	// number, for APIs that quote their numeric values.
+	JSONNumberAsString bool `json:"-"`
+	// ChannelsAsStreams describes channel fields by their element type,
+	// marked with "x-stream": true, instead of reporting them as
+	// unsupported.
+	ChannelsAsStreams bool `json:"-"`
+	// TypedMapsAsAdditionalProperties describes the values of maps by an
+	// "additionalProperties" schema, the standard form, instead of a ".*"
+	// property.
+	TypedMapsAsAdditionalProperties bool `json:"-"`
+	// ByteArraysAsBase64 describes fixed size byte arrays, e.g. [32]byte
+	// hashes, as base64 strings of the matching length. encoding/json
+	// encodes them as arrays of numbers, so this is for types with a
+	// marshaler encoding them like byte slices.
+	ByteArraysAsBase64 bool `json:"-"`
+	// InferExamples records the value of every scalar field read by
+	// ReadDeep as the only entry of its "examples", which documents schemas
+	// generated from sample data.
+	InferExamples bool `json:"-"`
+	// SingleExample emits the first of the examples of every property as
+	// "example", for OpenAPI 3.0 schemas, which don't know "examples".
+	SingleExample bool `json:"-"`
+	// InferNilPointerTypes makes ReadDeep describe nil pointers by their
+	// element type, as Read does, instead of emitting them as null.
+	InferNilPointerTypes bool `json:"-"`
+	// StringEnums emits every enum value as a string, e.g. "1" for an
+	// integer, for validators that only accept string enums. The type of
+	// the property is kept.
+	StringEnums bool `json:"-"`
+	// InferIntegerBounds sets the minimum and maximum of integers to the
+	// range of their Go type, e.g. 0 and 255 for uint8.
+	InferIntegerBounds bool `json:"-"`
+	// SchemaTagName is the key of the struct tag holding the schema
+	// keywords, e.g. "schema" to share the tags of another library. It
+	// defaults to "jsonschema".
+	SchemaTagName string `json:"-"`
+	// UseValidatorTags translates the common rules of the `validate` tags
+	// of github.com/go-playground/validator, such as required, min, max and
+	// email, into schema keywords. The jsonschema tag takes precedence.
+	UseValidatorTags bool `json:"-"`
+	// WriteOnlyPattern marks the properties whose name matches as
+	// writeOnly, e.g. DefaultWriteOnlyPattern for passwords and tokens.
+	WriteOnlyPattern *regexp.Regexp `json:"-"`
+
+	overrides  map[string]*property
+	contains   map[string]*property
+	dependents map[string]map[string]*property
+	enums      map[reflect.Type]*property
+	open       map[reflect.Type]bool
+	formats    map[reflect.Type][]string
+	generators map[reflect.Type]func() *property
+	impls      map[reflect.Type][]reflect.Type
+	orders     map[string][]string
+	defNames   map[reflect.Type]string
+	defined    map[string]*property
+	definedAs  map[reflect.Type]string
+	fields     map[fieldKey]*property
+	err        error
+	path       string
 }
 
 // NewDocument creates a new JSON-Schema Document with the specified schema.
+// Known dialect URLs are normalized to their canonical form.
 func NewDocument(schema string) *Document {
+	schema, _ = NormalizeSchema(schema)
+
 	return &Document{
 		Schema: schema,
 	}
@@ -26,148 +161,724 @@ func NewDocument(schema string) *Document {
 
 // Reads the variable structure into the JSON-Schema Document
 func (d *Document) Read(variable interface{}) {
+	d.ReadType(reflect.TypeOf(variable))
+}
+
+// TryRead reads the variable structure into the JSON-Schema Document and
+// returns the first problem found while doing so.
+func (d *Document) TryRead(variable interface{}) error {
+	return d.readType(reflect.TypeOf(variable))
+}
+
+// ReadType reads the Go type t into the JSON-Schema Document
+func (d *Document) ReadType(t reflect.Type) {
+	_ = d.readType(t)
+}
+
+func (d *Document) readType(t reflect.Type) error {
+	d.err = nil
 	d.setDefaultSchema()
 
-	value := reflect.ValueOf(variable)
-	d.read(value.Type(), "")
+	key, cacheable := d.cacheKey(t)
+	if cacheable {
+		if cached, ok := d.readCached(key); ok {
+			d.property = *cached
+			d.addDefined()
+			d.finishRead()
+			return d.err
+		}
+	}
+
+	// The schema is read afresh, with the problems of $schema set aside,
+	// so that only what the type itself produced is cached.
+	schemaErr := d.err
+	d.err = nil
+	d.property = property{}
+	d.defNames = d.definedTypeNames()
+	d.fields = nil
+	d.property.read(d, t, "")
+	d.fields = nil
+	if d.InlineSingleUse {
+		d.inlineSingleUse()
+	}
+	if cacheable {
+		d.storeCached(key)
+	}
+	readErr := d.err
+	d.err = schemaErr
+	d.fail(readErr)
+	d.addDefined()
+	d.finishRead()
+
+	return d.err
 }
 
 // ReadDeep reads the variable structure into the JSON-Schema Document
 func (d *Document) ReadDeep(variable interface{}) {
+	d.ReadValue(reflect.ValueOf(variable))
+}
+
+// TryReadDeep reads the variable structure into the JSON-Schema Document and
+// returns the first problem found while doing so.
+func (d *Document) TryReadDeep(variable interface{}) error {
+	return d.readValue(reflect.ValueOf(variable))
+}
+
+// ReadValue reads the runtime value v into the JSON-Schema Document,
+// inspecting the contents of maps, slices and interfaces like ReadDeep.
+func (d *Document) ReadValue(v reflect.Value) {
+	_ = d.readValue(v)
+}
+
+func (d *Document) readValue(v reflect.Value) error {
+	d.err = nil
 	d.setDefaultSchema()
 
-	value := reflect.ValueOf(variable)
-	d.readDeep(value, "")
+	d.property.readDeep(d, v, "")
+	d.finishRead()
+
+	return d.err
+}
+
+// finishRead applies the registrations and options that act on the schema
+// as a whole once it has been read, and checks its values.
+func (d *Document) finishRead() {
+	d.applyOverrides()
+	d.finish("", &d.property)
+}
+
+// finish applies the options that act on every property of root, found at
+// path, once it has been read, and checks its values.
+func (d *Document) finish(path string, root *property) {
+	d.stringifyEnums(root)
+	if d.DisableFormats {
+		root.forEach(func(p *property) {
+			if pattern, ok := d.FormatFallbacks[p.Format]; ok && p.Pattern == "" {
+				p.Pattern = pattern
+			}
+			p.Format = ""
+		})
+	}
+	if d.Schema == Draft202012 {
+		// Draft 2020-12 replaced the array form of items with prefixItems.
+		root.forEach(func(p *property) {
+			p.PrefixItems = p.TupleItems != nil
+		})
+	}
+	if d.Schema == Draft04 {
+		// propertyNames was added in draft-06. The schema isn't part of
+		// the cache key, so it is dropped here rather than while reading.
+		root.forEach(func(p *property) {
+			p.PropertyNames = nil
+		})
+	}
+	if d.SingleExample {
+		root.forEach(func(p *property) {
+			if len(p.Examples) > 0 {
+				p.Example, p.Examples = p.Examples[0], nil
+			}
+		})
+	}
+	if d.EmptyProperties {
+		root.forEach(func(p *property) {
+			if p.Type == "object" && p.Properties == nil {
+				p.Properties = make(map[string]*property)
+			}
+		})
+	}
+	d.fail(root.checkTree(path))
+}
+
+// fail records err, keeping only the first problem of a read.
+func (d *Document) fail(err error) {
+	if d.err == nil && err != nil {
+		d.err = err
+	}
+}
+
+// failAt records a GenerationError for the property currently being read.
+func (d *Document) failAt(t reflect.Type, reason string) {
+	d.fail(&GenerationError{Path: d.path, Type: t.String(), Reason: reason})
+}
+
+// Override replaces the generated property at the dotted fieldPath with p.
+// The path is made of the property names from the root, not starting with
+// the name of the root type: "Address.Zip" for the Zip of the Address of a
+// User. With UseDefinitions, the path continues into the definition a
+// property refers to, so the override applies wherever it is referenced.
+// Overrides are applied after the type has been read. Paths that don't
+// exist in the generated schema are ignored, or reported in Strict mode.
+func (d *Document) Override(fieldPath string, p *Schema) {
+	if d.overrides == nil {
+		d.overrides = make(map[string]*property)
+	}
+	d.overrides[fieldPath] = p
+}
+
+// SetContains requires the array at the dotted fieldPath (see Override) to
+// contain at least one item matching p, e.g. a user with the admin role.
+// Like overrides, it is applied after the type has been read. The contains
+// keyword was added in draft-06, so it is reported as an error for draft-04
+// documents. The number of matching items can be limited with the
+// minContains and maxContains tags of the field, for draft 2019-09 and later.
+func (d *Document) SetContains(fieldPath string, p *Schema) {
+	if d.contains == nil {
+		d.contains = make(map[string]*property)
+	}
+	d.contains[fieldPath] = p
+}
+
+// SetDependentSchema applies p to the object at the dotted fieldPath (see
+// Override), or the root for "", whenever its property name is present, e.g.
+// requiring a billing address when a credit card is given. It is applied after the
+// type has been read and requires draft 2019-09 or later, where the
+// dependentSchemas keyword was introduced.
+func (d *Document) SetDependentSchema(fieldPath, name string, p *Schema) {
+	if d.dependents == nil {
+		d.dependents = make(map[string]map[string]*property)
+	}
+	if d.dependents[fieldPath] == nil {
+		d.dependents[fieldPath] = make(map[string]*property)
+	}
+	d.dependents[fieldPath][name] = p
+}
+
+// applyOverrides applies the registrations of Override, SetContains and
+// SetDependentSchema. Overrides are applied in the order of their paths, so
+// that those of nested fields apply to the override of their parent. The
+// registered schemas are copied, as the options applied after them would
+// otherwise modify the caller's.
+func (d *Document) applyOverrides() {
+	for _, fieldPath := range sortedKeys(d.overrides) {
+		parent, last := &d.property, fieldPath
+		if i := strings.LastIndex(fieldPath, "."); i >= 0 {
+			parent, last = d.propertyAt(fieldPath[:i]), fieldPath[i+1:]
+		}
+		if parent == nil || parent.Properties[last] == nil {
+			d.failUnresolved(fieldPath)
+			continue
+		}
+		parent.Properties[last] = d.overrides[fieldPath].clone()
+	}
+
+	for _, fieldPath := range sortedKeys(d.contains) {
+		array := d.propertyAt(fieldPath)
+		switch {
+		case array == nil:
+			d.failUnresolved(fieldPath)
+		case d.Schema == Draft04:
+			d.fail(&GenerationError{Path: fieldPath, Reason: "contains requires draft-06 or later"})
+		case array.Type != "array":
+			d.fail(&GenerationError{Path: fieldPath, Reason: "contains requires an array"})
+		case (array.MinContains != nil || array.MaxContains != nil) && !usesDefs(d.Schema):
+			d.fail(&GenerationError{Path: fieldPath, Reason: "minContains and maxContains require draft 2019-09 or later"})
+		default:
+			array.Contains = d.contains[fieldPath].clone()
+		}
+	}
+
+	fieldPaths := make([]string, 0, len(d.dependents))
+	for fieldPath := range d.dependents {
+		fieldPaths = append(fieldPaths, fieldPath)
+	}
+	sort.Strings(fieldPaths)
+	for _, fieldPath := range fieldPaths {
+		object := d.propertyAt(fieldPath)
+		switch {
+		case object == nil:
+			d.failUnresolved(fieldPath)
+		case !usesDefs(d.Schema):
+			d.fail(&GenerationError{Path: fieldPath, Reason: "dependentSchemas requires draft 2019-09 or later"})
+		case object.Type != "object":
+			d.fail(&GenerationError{Path: fieldPath, Reason: "dependentSchemas requires an object"})
+		default:
+			if object.DependentSchemas == nil {
+				object.DependentSchemas = make(map[string]*property, len(d.dependents[fieldPath]))
+			}
+			for name, schema := range d.dependents[fieldPath] {
+				object.DependentSchemas[name] = schema.clone()
+			}
+		}
+	}
+}
+
+// propertyAt returns the property at the dotted fieldPath, the root for "",
+// or nil. References to definitions are followed.
+func (d *Document) propertyAt(fieldPath string) *property {
+	p := &d.property
+	if fieldPath == "" {
+		return p
+	}
+	for _, name := range strings.Split(fieldPath, ".") {
+		if p = d.resolve(p).Properties[name]; p == nil {
+			return nil
+		}
+	}
+
+	return d.resolve(p)
+}
+
+// resolve returns the definition p refers to, or p itself.
+func (d *Document) resolve(p *property) *property {
+	if p.Ref == "" || !strings.HasPrefix(p.Ref, d.refPrefix()) {
+		return p
+	}
+	if definition, ok := d.Definitions[strings.TrimPrefix(p.Ref, d.refPrefix())]; ok {
+		return definition
+	}
+
+	return p
+}
+
+// failUnresolved reports, in Strict mode, a registration for a fieldPath
+// that doesn't exist in the generated schema.
+func (d *Document) failUnresolved(fieldPath string) {
+	if d.Strict {
+		d.fail(&GenerationError{Path: fieldPath, Reason: "no property at the path"})
+	}
+}
+
+// SetLoose makes the Document generate the most permissive schema of a
+// type, for ingesting data that may not follow it closely: no property is
+// required, additional properties are allowed and formats are left out.
+func (d *Document) SetLoose() {
+	d.AllOptional = true
+	d.AllOpen = true
+	d.DisableFormats = true
+}
+
+func (d *Document) isRequired(field reflect.StructField, opts tagOptions) bool {
+	if d.AllOptional {
+		return false
+	}
+	if d.IsRequired != nil {
+		return d.IsRequired(field, opts)
+	}
+	if d.UseValidatorTags && hasValidatorRule(field.Tag.Get("validate"), "required") {
+		return true
+	}
+
+	return !opts.Contains("omitempty")
 }
 
 func (d *Document) setDefaultSchema() {
 	if d.Schema == "" {
 		d.Schema = defaultSchema
+		return
 	}
+
+	schema, known := NormalizeSchema(d.Schema)
+	if !known && d.Strict {
+		d.fail(fmt.Errorf("%w: %q", ErrUnknownSchema, d.Schema))
+	}
+	d.Schema = schema
 }
 
 // Marshal returns the JSON encoding of the Document
 func (d *Document) Marshal() ([]byte, error) {
-	return json.MarshalIndent(d, "", "    ")
+	out, err := json.MarshalIndent(d, "", "    ")
+	if err != nil {
+		if valueErr := d.checkValues(); valueErr != nil {
+			return nil, valueErr
+		}
+	}
+
+	return out, err
 }
 
-// String return the JSON encoding of the Document as a string
+// String returns the indented JSON encoding of the Document, or the error
+// that prevented encoding it.
 func (d *Document) String() string {
-	jsonBytes, _ := d.Marshal()
+	jsonBytes, err := d.Marshal()
+	if err != nil {
+		return err.Error()
+	}
 	return string(jsonBytes)
 }
 
+// Schema is a JSON-Schema, as passed to Override and RegisterType or by
+// Walk, with the keywords of the Document itself.
+type Schema = property
+
 type property struct {
-	Type                 string               `json:"type,omitempty"`
-	Format               string               `json:"format,omitempty"`
-	Items                *property            `json:"items,omitempty"`
-	Properties           map[string]*property `json:"properties,omitempty"`
-	Required             []string             `json:"required,omitempty"`
-	AdditionalProperties bool                 `json:"additionalProperties,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Nullable             bool                   `json:"-"`
+	Format               string                 `json:"format,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Minimum              interface{}            `json:"minimum,omitempty"`
+	Maximum              interface{}            `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Items                *property              `json:"items,omitempty"`
+	TupleItems           []*property            `json:"-"`
+	PrefixItems          bool                   `json:"-"`
+	AdditionalItems      *bool                  `json:"additionalItems,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Contains             *property              `json:"contains,omitempty"`
+	MinContains          *int                   `json:"minContains,omitempty"`
+	MaxContains          *int                   `json:"maxContains,omitempty"`
+	Properties           map[string]*property   `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	DependentSchemas     map[string]*property   `json:"dependentSchemas,omitempty"`
+	AdditionalProperties bool                   `json:"additionalProperties,omitempty"`
+	AdditionalSchema     *property              `json:"-"`
+	PropertyNames        *property              `json:"propertyNames,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Const                interface{}            `json:"const,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Examples             []interface{}          `json:"examples,omitempty"`
+	Example              interface{}            `json:"example,omitempty"`
+	Comment              string                 `json:"$comment,omitempty"`
+	WriteOnly            bool                   `json:"writeOnly,omitempty"`
+	AllOf                []*property            `json:"allOf,omitempty"`
+	AnyOf                []*property            `json:"anyOf,omitempty"`
+	OneOf                []*property            `json:"oneOf,omitempty"`
+	If                   *property              `json:"if,omitempty"`
+	Then                 *property              `json:"then,omitempty"`
+	Definitions          map[string]*property   `json:"definitions,omitempty"`
+	Extensions           map[string]interface{} `json:"-"`
+	Order                []string               `json:"-"`
 }
 
-func (p *property) read(t reflect.Type, opts tagOptions) {
-	jsType, format, kind := getTypeFromMapping(t)
+func (p *property) read(d *Document, t reflect.Type, opts tagOptions) {
+	if d.readRegistered(p, t) {
+		return
+	}
+	if isOpaque(t) {
+		p.readOpaque(d, t)
+		return
+	}
+	if t == rawMessageType {
+		// Any JSON value, e.g. the values of a map[string]json.RawMessage,
+		// which then allows additional properties.
+		return
+	}
+	if d.UseDefinitions && d.SharedDateTime && t == timeType && p != &d.property {
+		p.readDateTimeDefinition(d)
+		return
+	}
+
+	jsType, format, kind := d.getTypeFromMapping(t)
 	if jsType != "" {
 		p.Type = jsType
 	}
 	if format != "" {
 		p.Format = format
 	}
+	if d.InferIntegerBounds {
+		p.readIntegerBounds(t, kind)
+	}
 
 	switch kind {
 	case reflect.Slice:
-		p.readFromSlice(t)
+		p.readFromSlice(d, t)
+	case reflect.Array:
+		if d.ByteArraysAsBase64 && isByteSlice(t) {
+			p.readBase64Array(t)
+		} else {
+			p.readFromArray(d, t)
+		}
 	case reflect.Map:
-		p.readFromMap(t)
+		p.readFromMap(d, t)
 	case reflect.Struct:
-		p.readFromStruct(t)
+		if d.UseDefinitions && t.Name() != "" && p != &d.property {
+			p.readDefinition(d, t)
+		} else {
+			p.readFromStruct(d, t)
+		}
 	case reflect.Ptr:
-		p.read(t.Elem(), opts)
+		p.read(d, t.Elem(), opts)
+		p.readNullableEnum(d, t)
+	case reflect.Chan:
+		p.readFromChan(d, t)
+	case reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		d.failAt(t, "unsupported type")
 	}
 }
 
-func (p *property) readDeep(v reflect.Value, opts tagOptions) {
+func (p *property) readDeep(d *Document, v reflect.Value, opts tagOptions) {
 	if !v.IsValid() {
 		p.Type = "null"
 		return
 	}
-	jsType, format, kind := getTypeFromMapping(v.Type())
+	if d.readRegistered(p, v.Type()) {
+		return
+	}
+	if isOpaque(v.Type()) {
+		p.readOpaque(d, v.Type())
+		return
+	}
+	if v.Type() == rawMessageType {
+		return
+	}
+
+	jsType, format, kind := d.getTypeFromMapping(v.Type())
 	if jsType != "" {
 		p.Type = jsType
 	}
 	if format != "" {
 		p.Format = format
 	}
+	if d.InferIntegerBounds {
+		p.readIntegerBounds(v.Type(), kind)
+	}
+
+	if d.InferExamples {
+		if example, ok := exampleValue(v, kind); ok {
+			p.Examples = []interface{}{example}
+		}
+	}
 
 	switch kind {
 	case reflect.Slice:
-		p.readFromSliceDeep(v)
+		p.readFromSliceDeep(d, v)
+	case reflect.Array:
+		if d.ByteArraysAsBase64 && isByteSlice(v.Type()) {
+			p.readBase64Array(v.Type())
+		} else {
+			p.readFromArray(d, v.Type())
+		}
 	case reflect.Map:
-		p.readFromMapDeep(v)
+		p.readFromMapDeep(d, v)
 	case reflect.Struct:
-		p.readFromStructDeep(v)
+		p.readFromStructDeep(d, v)
 	case reflect.Ptr, reflect.Interface:
-		p.readDeep(v.Elem(), opts)
+		if kind == reflect.Ptr && v.IsNil() && d.InferNilPointerTypes {
+			p.read(d, v.Type().Elem(), opts)
+			p.readNullableEnum(d, v.Type())
+			return
+		}
+		p.readDeep(d, v.Elem(), opts)
+		if kind == reflect.Ptr && !v.IsNil() {
+			p.readNullableEnum(d, v.Type())
+		}
+	case reflect.Chan:
+		p.readFromChan(d, v.Type())
+	case reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		d.failAt(v.Type(), "unsupported type")
 	}
 }
 
-func (p *property) readFromSlice(t reflect.Type) {
-	jsType, _, kind := getTypeFromMapping(t.Elem())
-	if kind == reflect.Uint8 {
+// readNullableEnum makes p, read from the pointer type t, nullable when t
+// points to a registered enum, as the enum wouldn't accept the null of a nil
+// pointer otherwise.
+func (p *property) readNullableEnum(d *Document, t reflect.Type) {
+	if _, ok := d.enums[t.Elem()]; ok && p != &d.property {
+		p.Nullable = true
+	}
+}
+
+// readIntegerBounds sets the minimum and maximum of an integer of the given
+// kind to the range of the type t.
+func (p *property) readIntegerBounds(t reflect.Type, kind reflect.Kind) {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		max := int64(1)<<(t.Bits()-1) - 1
+		p.Minimum, p.Maximum = -max-1, max
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		p.Minimum, p.Maximum = uint64(0), uint64(1)<<(t.Bits()-1)<<1-1
+	}
+}
+
+// exampleValue returns the value of a scalar v for use as an example. Types
+// mapped to a scalar, such as time.Time, qualify only when they marshal
+// themselves, and NaN and infinity are left out as JSON can't encode them.
+func exampleValue(v reflect.Value, kind reflect.Kind) (interface{}, bool) {
+	switch kind {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	case reflect.Float32, reflect.Float64:
+		if v.Kind() == kind && (math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0)) {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	if !v.CanInterface() {
+		return nil, false
+	}
+	if v.Type() == jsonNumberType && kind == reflect.String {
+		return v.String(), true
+	}
+	if v.Kind() != kind && v.Type() != jsonNumberType && !v.Type().Implements(textMarshalerType) && !v.Type().Implements(jsonMarshalerType) {
+		return nil, false
+	}
+
+	return v.Interface(), true
+}
+
+func (p *property) readFromSlice(d *Document, t reflect.Type) {
+	if isByteSlice(t) {
 		p.Type = "string"
-	} else if jsType != "" {
-		p.Items = &property{}
-		p.Items.read(t.Elem(), "")
+		return
+	}
+
+	parent := d.path
+	d.path += "[]"
+	items := &property{}
+	items.read(d, t.Elem(), "")
+	d.path = parent
+
+	if !items.isEmpty() {
+		p.Items = items
 	}
 }
 
-func (p *property) readFromSliceDeep(v reflect.Value) {
-	if v.Len() == 0 {
-		t := v.Type()
-		jsType, _, kind := getTypeFromMapping(t.Elem())
-		if kind == reflect.Uint8 {
-			p.Type = "string"
-		} else if jsType != "" {
-			p.Items = &property{}
-			if v.Len() == 0 {
-				p.Items.read(t.Elem(), "")
-				return
-			}
-			p.Items.readDeep(v.Index(0), "")
+// readFromChan describes a channel by its element type when
+// ChannelsAsStreams is set, marking it with the x-stream extension.
+func (p *property) readFromChan(d *Document, t reflect.Type) {
+	if !d.ChannelsAsStreams {
+		d.failAt(t, "unsupported type")
+		return
+	}
+
+	p.read(d, t.Elem(), "")
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions["x-stream"] = true
+}
+
+// isByteSlice reports whether the slice type t is encoded as a base64
+// string, which encoding/json does unless the element marshals itself. It
+// is also used for byte arrays with ByteArraysAsBase64.
+func isByteSlice(t reflect.Type) bool {
+	return t.Elem().Kind() == reflect.Uint8 && !marshalsItself(t.Elem())
+}
+
+// isEmpty reports whether p places no constraint at all, as for interface
+// types.
+func (p *property) isEmpty() bool {
+	return reflect.ValueOf(*p).IsZero()
+}
+
+// readBase64Array describes the byte array type t as a base64 string, whose
+// length is fixed by the size of the array.
+func (p *property) readBase64Array(t reflect.Type) {
+	length := base64.StdEncoding.EncodedLen(t.Len())
+	p.Type = "string"
+	p.ContentEncoding = "base64"
+	p.MinLength = &length
+	p.MaxLength = &length
+}
+
+func (p *property) readFromArray(d *Document, t reflect.Type) {
+	length := t.Len()
+	p.MinItems = &length
+	p.MaxItems = &length
+
+	parent := d.path
+	d.path += "[]"
+	items := &property{}
+	items.read(d, t.Elem(), "")
+	d.path = parent
+
+	if !items.isEmpty() {
+		p.Items = items
+	}
+}
+
+// readTuple describes each position of the fixed length array t with its
+// own item schema and forbids any further items.
+func (p *property) readTuple(d *Document, t reflect.Type) {
+	p.Items = nil
+	p.TupleItems = make([]*property, t.Len())
+	for i := range p.TupleItems {
+		parent := d.path
+		d.path = fmt.Sprintf("%s[%d]", parent, i)
+		p.TupleItems[i] = &property{}
+		p.TupleItems[i].read(d, t.Elem(), "")
+		d.path = parent
+	}
+
+	additionalItems := false
+	p.AdditionalItems = &additionalItems
+}
+
+// readFromSliceDeep infers the items from the first element that isn't nil,
+// falling back to the static element type when there is none.
+func (p *property) readFromSliceDeep(d *Document, v reflect.Value) {
+	first := -1
+	for i := 0; i < v.Len(); i++ {
+		if !isNilValue(v.Index(i)) {
+			first = i
+			break
 		}
+	}
+
+	if first == -1 || isByteSlice(v.Type()) {
+		p.readFromSlice(d, v.Type())
 		return
 	}
 
-	_, _, kind := getTypeFromMapping(v.Index(0).Type())
-	if kind == reflect.Uint8 {
-		p.Type = "string"
-	} else {
-		p.Items = &property{}
-		p.Items.readDeep(v.Index(0), "")
+	parent := d.path
+	d.path += "[]"
+	p.Items = &property{}
+	p.Items.readDeep(d, v.Index(first), "")
+	d.path = parent
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	default:
+		return !v.IsValid()
 	}
 }
 
-func (p *property) readFromMap(t reflect.Type) {
-	jsType, format, _ := getTypeFromMapping(t.Elem())
+// readFromMap describes the values of a map as its ".*" property, or its
+// additionalProperties with TypedMapsAsAdditionalProperties. Keys of a
+// type with a format, e.g. uuid.UUID, are described by propertyNames, which
+// finishRead drops for draft-04.
+func (p *property) readFromMap(d *Document, t reflect.Type) {
+	if _, format, _ := d.getTypeFromMapping(t.Key()); format != "" {
+		p.PropertyNames = &property{Type: "string", Format: format}
+	}
 
-	if jsType != "" {
-		p.Properties = make(map[string]*property, 0)
-		p.Properties[".*"] = &property{Type: jsType, Format: format}
-	} else {
+	parent := d.path
+	d.path = joinPath(parent, "*")
+	value := &property{}
+	value.read(d, t.Elem(), "")
+	d.path = parent
+
+	switch {
+	case value.isEmpty():
 		p.AdditionalProperties = true
+	case d.TypedMapsAsAdditionalProperties:
+		p.AdditionalSchema = value
+	default:
+		p.Properties = make(map[string]*property, 0)
+		p.Properties[".*"] = value
 	}
 }
 
-func (p *property) readFromMapDeep(v reflect.Value) {
+// readFromMapDeep describes each entry of the map. An empty map is
+// described by its static value type, as Read does.
+func (p *property) readFromMapDeep(d *Document, v reflect.Value) {
+	if v.Len() == 0 {
+		p.readFromMap(d, v.Type())
+		return
+	}
+
 	properties := make(map[string]*property)
 	iter := v.MapRange()
 	for iter.Next() {
 		key := iter.Key()
 		value := iter.Value()
 		keyName := mapKeyToString(key)
+		parent := d.path
+		d.path = joinPath(parent, keyName)
 		properties[keyName] = &property{}
-		properties[keyName].readDeep(value, "")
+		properties[keyName].readDeep(d, value, "")
+		d.path = parent
 	}
 
 	if len(properties) > 0 {
@@ -185,10 +896,12 @@ func mapKeyToString(key reflect.Value) string {
 	return key.String()
 }
 
-func (p *property) readFromStruct(t reflect.Type) {
+func (p *property) readFromStruct(d *Document, t reflect.Type) {
 	p.Type = "object"
 	p.Properties = make(map[string]*property, 0)
-	p.AdditionalProperties = false
+	p.AdditionalProperties = d.open[t] || d.AllOpen
+	origins := make(map[string]string)
+	var bases []*property
 
 	count := t.NumField()
 	for i := 0; i < count; i++ {
@@ -199,15 +912,39 @@ func (p *property) readFromStruct(t reflect.Type) {
 		if name == "" {
 			name = field.Name
 		}
-		if name == "-" {
+		if tag == "-" {
+			if isCatchAll(field) {
+				p.AdditionalProperties = true
+			}
+			continue
+		}
+		if field.PkgPath != "" && !field.Anonymous {
+			// Unexported fields are left out by encoding/json.
 			continue
 		}
 
-		if field.Anonymous {
+		if isEmbeddedStruct(field) && isOpaque(derefType(field.Type)) {
+			// e.g. an embedded sync.Mutex, which has no fields to promote.
+			continue
+		}
+		if isEmbeddedStruct(field) {
 			embeddedProperty := &property{}
-			embeddedProperty.read(field.Type, opts)
+			_, _, kind := d.getTypeFromMapping(derefType(field.Type))
+			if kind == reflect.Struct && d.EmbedAsAllOf {
+				embeddedProperty.readDefinition(d, derefType(field.Type))
+				bases = append(bases, embeddedProperty)
+				d.checkEmbedding(t, field, d.Definitions[strings.TrimPrefix(embeddedProperty.Ref, d.refPrefix())])
+				continue
+			}
+			if kind == reflect.Struct {
+				embeddedProperty.readFromStruct(d, derefType(field.Type))
+				d.checkEmbedding(t, field, embeddedProperty)
+			} else {
+				embeddedProperty.read(d, field.Type, opts)
+			}
 
 			for name, property := range embeddedProperty.Properties {
+				d.checkCollision(t, origins, name, field.Name+"."+name)
 				p.Properties[name] = property
 			}
 			p.Required = append(p.Required, embeddedProperty.Required...)
@@ -215,20 +952,85 @@ func (p *property) readFromStruct(t reflect.Type) {
 			continue
 		}
 
-		p.Properties[name] = &property{}
-		p.Properties[name].read(field.Type, opts)
+		d.checkCollision(t, origins, name, field.Name)
+		parent := d.path
+		d.path = joinPath(parent, name)
+		p.Properties[name] = d.readField(field, opts)
+		d.path = parent
 
-		if !opts.Contains("omitempty") {
+		if d.OmitemptyAsNullable && opts.Contains("omitempty") {
+			p.Properties[name].Nullable = true
+		}
+		if d.WriteOnlyPattern != nil && d.WriteOnlyPattern.MatchString(name) {
+			p.Properties[name].WriteOnly = true
+		}
+
+		if d.isRequired(field, opts) {
 			p.Required = append(p.Required, name)
 		}
 	}
+
+	if len(p.Properties) == 0 && !d.EmptyProperties {
+		p.Properties = nil
+	}
+	p.Order = d.propertyOrderOf(t, p.Properties)
+	if len(bases) > 0 {
+		local := &property{
+			Type:                 "object",
+			Properties:           p.Properties,
+			Required:             p.Required,
+			AdditionalProperties: p.AdditionalProperties,
+			Order:                p.Order,
+		}
+		p.Properties, p.Required, p.AdditionalProperties, p.Order = nil, nil, false, nil
+		p.AllOf = append(bases, local)
+	}
+}
+
+// isEmbeddedStruct reports whether the fields of field are promoted into its
+// parent, which encoding/json does for embedded structs and pointers to
+// structs unless their json tag gives them a name. Other embedded types,
+// such as interfaces, are encoded as a field named after their type.
+func isEmbeddedStruct(field reflect.StructField) bool {
+	name, _ := parseTag(field.Tag.Get("json"))
+	return field.Anonymous && name == "" && derefType(field.Type).Kind() == reflect.Struct
+}
+
+// isCatchAll reports whether the field, which is left out by its json tag,
+// holds the properties without a field of their own, as a map from strings
+// filled by custom (un)marshalers does. Its struct then allows additional
+// properties.
+func isCatchAll(field reflect.StructField) bool {
+	t := derefType(field.Type)
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String
 }
 
-func (p *property) readFromStructDeep(v reflect.Value) {
+// checkCollision reports, in Strict mode, a property name of the struct t
+// that is defined by more than one field, e.g. by an outer field and one
+// promoted from an embedded struct. origins maps the names seen so far to
+// the fields defining them.
+func (d *Document) checkCollision(t reflect.Type, origins map[string]string, name, origin string) {
+	if previous, ok := origins[name]; ok && d.Strict {
+		d.failAt(t, fmt.Sprintf("property %q is defined by both %s and %s", name, previous, origin))
+	}
+	origins[name] = origin
+}
+
+// checkEmbedding reports, in Strict mode, an embedded struct of t that
+// promotes no properties, e.g. one without exported fields, as embedding it
+// has no effect on the schema.
+func (d *Document) checkEmbedding(t reflect.Type, field reflect.StructField, embedded *property) {
+	if d.Strict && embedded != nil && len(embedded.Properties) == 0 {
+		d.failAt(t, fmt.Sprintf("embedded %s promotes no properties", field.Name))
+	}
+}
+
+func (p *property) readFromStructDeep(d *Document, v reflect.Value) {
 	t := v.Type()
 	p.Type = "object"
 	p.Properties = make(map[string]*property, 0)
-	p.AdditionalProperties = false
+	p.AdditionalProperties = d.open[t] || d.AllOpen
+	origins := make(map[string]string)
 
 	count := t.NumField()
 	for i := 0; i < count; i++ {
@@ -239,15 +1041,33 @@ func (p *property) readFromStructDeep(v reflect.Value) {
 		if name == "" {
 			name = field.Name
 		}
-		if name == "-" {
+		if tag == "-" {
+			if isCatchAll(field) {
+				p.AdditionalProperties = true
+			}
+			continue
+		}
+		if field.PkgPath != "" && !field.Anonymous {
+			// Unexported fields are left out by encoding/json.
 			continue
 		}
 
-		if field.Anonymous {
+		if isEmbeddedStruct(field) && isOpaque(derefType(field.Type)) {
+			// e.g. an embedded sync.Mutex, which has no fields to promote.
+			continue
+		}
+		if isEmbeddedStruct(field) {
 			embeddedProperty := &property{}
-			embeddedProperty.readDeep(v.Field(i), opts)
+			if value := v.Field(i); value.Kind() == reflect.Ptr && value.IsNil() {
+				// Promoted by their type, as other values may set them.
+				embeddedProperty.readFromStruct(d, derefType(field.Type))
+			} else {
+				embeddedProperty.readDeep(d, value, opts)
+			}
+			d.checkEmbedding(t, field, embeddedProperty)
 
 			for name, property := range embeddedProperty.Properties {
+				d.checkCollision(t, origins, name, field.Name+"."+name)
 				p.Properties[name] = property
 			}
 			p.Required = append(p.Required, embeddedProperty.Required...)
@@ -255,19 +1075,75 @@ func (p *property) readFromStructDeep(v reflect.Value) {
 			continue
 		}
 
+		d.checkCollision(t, origins, name, field.Name)
+		parent := d.path
+		d.path = joinPath(parent, name)
 		p.Properties[name] = &property{}
-		p.Properties[name].readDeep(v.Field(i), opts)
+		p.Properties[name].readDeep(d, v.Field(i), opts)
+		if d.UseValidatorTags {
+			p.Properties[name].readValidatorTag(d, field.Type, field.Tag.Get("validate"))
+		}
+		p.Properties[name].readSchemaTag(d, field.Type, d.schemaTag(field))
+		d.path = parent
+
+		if d.OmitemptyAsNullable && opts.Contains("omitempty") {
+			p.Properties[name].Nullable = true
+		}
+		if d.WriteOnlyPattern != nil && d.WriteOnlyPattern.MatchString(name) {
+			p.Properties[name].WriteOnly = true
+		}
 
-		if !opts.Contains("omitempty") {
+		if d.isRequired(field, opts) {
 			p.Required = append(p.Required, name)
 		}
 	}
+
+	if len(p.Properties) == 0 && !d.EmptyProperties {
+		p.Properties = nil
+	}
+	p.Order = d.propertyOrderOf(t, p.Properties)
 }
 
 var formatMapping = map[string][]string{
 	"time.Time": {"string", "date-time"},
 }
 
+// builtinFormatMapping recognizes well-known types by their package name,
+// so that e.g. both github.com/google/uuid and github.com/gofrs/uuid are
+// matched by "uuid.UUID". Only types that marshal themselves are matched,
+// as encoding/json encodes the others as objects. It can be turned off with
+// DisableBuiltinFormats.
+var builtinFormatMapping = map[string][]string{
+	"civil.Date": {"string", "date"},
+	"uuid.UUID":  {"string", "uuid"},
+}
+
+// opaqueTypes holds standard library types that keep their state in
+// unexported fields, which encoding/json encodes as empty objects. Reading
+// them would only describe their internals.
+var opaqueTypes = map[string]bool{
+	"sync.Map":          true,
+	"sync.Mutex":        true,
+	"sync.RWMutex":      true,
+	"sync.WaitGroup":    true,
+	"sync.Once":         true,
+	"sync/atomic.Value": true,
+}
+
+func isOpaque(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && opaqueTypes[t.PkgPath()+"."+t.Name()]
+}
+
+// readOpaque describes a type of opaqueTypes as an object allowing any
+// properties, which is reported as an error in Strict mode.
+func (p *property) readOpaque(d *Document, t reflect.Type) {
+	p.Type = "object"
+	p.AdditionalProperties = true
+	if d.Strict {
+		d.failAt(t, "type can't be serialized")
+	}
+}
+
 var kindMapping = map[reflect.Kind]string{
 	reflect.Bool:    "boolean",
 	reflect.Int:     "integer",
@@ -284,14 +1160,33 @@ var kindMapping = map[reflect.Kind]string{
 	reflect.Float64: "number",
 	reflect.String:  "string",
 	reflect.Slice:   "array",
+	reflect.Array:   "array",
 	reflect.Struct:  "object",
 	reflect.Map:     "object",
 }
 
-func getTypeFromMapping(t reflect.Type) (string, string, reflect.Kind) {
-	if v, ok := formatMapping[t.String()]; ok {
+func (d *Document) getTypeFromMapping(t reflect.Type) (string, string, reflect.Kind) {
+	if v, ok := d.formats[t]; ok {
 		return v[0], v[1], reflect.String
 	}
+	if v, ok := lookupFormatMapping(formatMapping, t); ok {
+		return v[0], v[1], reflect.String
+	}
+	if !d.DisableBuiltinFormats && marshalsItself(t) {
+		if v, ok := lookupFormatMapping(builtinFormatMapping, t); ok {
+			return v[0], v[1], reflect.String
+		}
+	}
+
+	if t == jsonNumberType {
+		if d.JSONNumberAsString {
+			return "string", "", reflect.String
+		}
+		return "number", "", reflect.Float64
+	}
+	if d.StringerAsString && t.Kind() == reflect.Struct && isStringer(t) {
+		return "string", "", reflect.String
+	}
 
 	kind := t.Kind()
 	if v, ok := kindMapping[kind]; ok {
@@ -301,8 +1196,37 @@ func getTypeFromMapping(t reflect.Type) (string, string, reflect.Kind) {
 	return "", "", kind
 }
 
+var (
+	stringerType   = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// isStringer reports whether t or a pointer to t implements fmt.Stringer.
+func isStringer(t reflect.Type) bool {
+	return t.Implements(stringerType) || reflect.PtrTo(t).Implements(stringerType)
+}
+
+// lookupFormatMapping finds t in mapping by its fully-qualified name
+// (import path and type name) first, falling back to the short name
+// reported by t.String(), e.g. "time.Time".
+func lookupFormatMapping(mapping map[string][]string, t reflect.Type) ([]string, bool) {
+	if t.Name() != "" && t.PkgPath() != "" {
+		if v, ok := mapping[t.PkgPath()+"."+t.Name()]; ok {
+			return v, true
+		}
+	}
+
+	v, ok := mapping[t.String()]
+	return v, ok
+}
+
 type tagOptions string
 
+// TagOptions are the options following the name in a json struct tag, as
+// passed to Document.IsRequired.
+type TagOptions = tagOptions
+
 func parseTag(tag string) (string, tagOptions) {
 	if idx := strings.Index(tag, ","); idx != -1 {
 		return tag[:idx], tagOptions(tag[idx+1:])
@@ -311,7 +1235,7 @@ func parseTag(tag string) (string, tagOptions) {
 }
 
 func (o tagOptions) Contains(optionName string) bool {
-	if len(o) == 0 {
+	if len(o) == 0 || optionName == "" {
 		return false
 	}
 
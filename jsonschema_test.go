@@ -1,11 +1,22 @@
 package jsonschema
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/losisin/go-jsonschema-generator/internal/uuid"
 	. "gopkg.in/check.v1"
 )
 
@@ -16,7 +27,7 @@ type propertySuite struct{}
 var _ = Suite(&propertySuite{})
 
 type ExampleJSONBasic struct {
-	Omitted    string  `json:"-,omitempty"`
+	Omitted    string  `json:"-"`
 	Bool       bool    `json:",omitempty"`
 	Integer    int     `json:",omitempty"`
 	Integer8   int8    `json:",omitempty"`
@@ -185,6 +196,64 @@ func (self *propertySuite) TestLoadEmbedded(c *C) {
 	})
 }
 
+type embeddedUnexported struct {
+	ID     int    `json:"id"`
+	Note   string `json:"note,omitempty"`
+	secret int
+}
+
+type embeddedUnexportedPointer struct {
+	Tag string `json:"tag"`
+}
+
+type ExampleJSONEmbeddedUnexported struct {
+	embeddedUnexported
+	*embeddedUnexportedPointer
+	Name string `json:"name"`
+}
+
+func TestEmbeddedUnexported(t *testing.T) {
+	field, _ := reflect.TypeOf(ExampleJSONEmbeddedUnexported{}).FieldByName("embeddedUnexported")
+	if !field.Anonymous || field.PkgPath == "" {
+		t.Fatal("expected an unexported embedded field")
+	}
+
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"id":   {Type: "integer"},
+			"note": {Type: "string"},
+			"tag":  {Type: "string"},
+			"name": {Type: "string"},
+		},
+		Required: []string{"id", "tag", "name"},
+	}
+
+	j := &Document{}
+	j.Read(&ExampleJSONEmbeddedUnexported{})
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{}
+	deep.ReadDeep(&ExampleJSONEmbeddedUnexported{embeddedUnexportedPointer: &embeddedUnexportedPointer{}})
+	if diff := cmp.Diff(expected, deep.property); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("InferExamples", func(t *testing.T) {
+		j := &Document{InferExamples: true}
+		j.ReadDeep(&ExampleJSONEmbeddedUnexported{embeddedUnexported: embeddedUnexported{ID: 7, secret: 8}})
+
+		if diff := cmp.Diff([]interface{}{7}, j.Properties["id"].Examples); diff != "" {
+			t.Error(diff)
+		}
+		if _, ok := j.Properties["secret"]; ok {
+			t.Error("unexpected property for the unexported field secret")
+		}
+	})
+}
+
 type ExampleJSONBasicMaps struct {
 	Maps           map[string]string `json:",omitempty"`
 	MapOfInterface map[string]interface{}
@@ -280,7 +349,8 @@ func TestLoadMapDeep(t *testing.T) {
 						},
 					},
 					"MapOfInterface": {
-						Type: "object",
+						Type:                 "object",
+						AdditionalProperties: true,
 					},
 				},
 				Required: []string{"MapOfInterface"},
@@ -536,3 +606,2329 @@ func TestLoadMapDeep(t *testing.T) {
 		}
 	})
 }
+
+type ExampleJSONOverrideAddress struct {
+	Street string
+	Zip    int
+}
+
+type ExampleJSONOverride struct {
+	Name    string
+	Address ExampleJSONOverrideAddress
+}
+
+func TestOverride(t *testing.T) {
+	t.Run("nested path", func(t *testing.T) {
+		j := &Document{}
+		j.Override("Address.Zip", &property{Type: "string", Format: "postal-code"})
+		j.Override("Name", &property{Type: "string", Enum: []interface{}{"alice", "bob"}})
+		j.Read(&ExampleJSONOverride{})
+
+		expected := map[string]*property{
+			"Name": {Type: "string", Enum: []interface{}{"alice", "bob"}},
+			"Address": {
+				Type: "object",
+				Properties: map[string]*property{
+					"Street": {Type: "string"},
+					"Zip":    {Type: "string", Format: "postal-code"},
+				},
+				Required: []string{"Street", "Zip"},
+			},
+		}
+		if diff := cmp.Diff(expected, j.Properties); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("deep read", func(t *testing.T) {
+		j := &Document{}
+		j.Override("Address.Zip", &property{Type: "string"})
+		j.ReadDeep(&ExampleJSONOverride{})
+
+		if j.Properties["Address"].Properties["Zip"].Type != "string" {
+			t.Errorf("override not applied: %+v", j.Properties["Address"].Properties["Zip"])
+		}
+	})
+	t.Run("unknown path is ignored", func(t *testing.T) {
+		j := &Document{}
+		j.Override("Address.Country.Code", &property{Type: "string"})
+		j.Override("Missing", &property{Type: "string"})
+		j.Read(&ExampleJSONOverride{})
+
+		if _, ok := j.Properties["Missing"]; ok {
+			t.Error("override created a property that wasn't generated")
+		}
+		if _, ok := j.Properties["Address"].Properties["Country"]; ok {
+			t.Error("override created a nested property that wasn't generated")
+		}
+	})
+	t.Run("definitions", func(t *testing.T) {
+		j := &Document{UseDefinitions: true}
+		j.Override("Address.Zip", &property{Type: "string", Format: "postal-code"})
+		j.Read(&ExampleJSONOverride{})
+
+		if ref := j.Properties["Address"].Ref; ref != "#/definitions/ExampleJSONOverrideAddress" {
+			t.Fatalf("Address is not a reference: %q", ref)
+		}
+		expected := &property{Type: "string", Format: "postal-code"}
+		if diff := cmp.Diff(expected, j.Definitions["ExampleJSONOverrideAddress"].Properties["Zip"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("unknown path in strict mode", func(t *testing.T) {
+		j := &Document{Strict: true}
+		j.Override("ExampleJSONOverride.Address.Zip", &property{Type: "string"})
+		err := j.TryRead(&ExampleJSONOverride{})
+
+		expected := "jsonschema: ExampleJSONOverride.Address.Zip: no property at the path"
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected %q, got %v", expected, err)
+		}
+	})
+	t.Run("parents first", func(t *testing.T) {
+		address := &property{
+			Type:       "object",
+			Properties: map[string]*property{"Zip": {Type: "integer"}},
+		}
+		for i := 0; i < 10; i++ {
+			j := &Document{}
+			j.Override("Address.Zip", &property{Type: "string", Format: "postal-code"})
+			j.Override("Address", address)
+			j.Read(&ExampleJSONOverride{})
+
+			if zip := j.Properties["Address"].Properties["Zip"]; zip.Format != "postal-code" {
+				t.Fatalf("read %d: nested override not applied: %+v", i, zip)
+			}
+		}
+		if zip := address.Properties["Zip"]; zip.Type != "integer" || zip.Format != "" {
+			t.Errorf("the registered override was modified: %+v", zip)
+		}
+	})
+}
+
+func TestReadPointerRoots(t *testing.T) {
+	ts := time.Now()
+	doublePointer := &ts
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected property
+	}{
+		{name: "time", value: &ts, expected: property{Type: "string", Format: "date-time"}},
+		{name: "pointer to pointer", value: &doublePointer, expected: property{Type: "string", Format: "date-time"}},
+		{name: "uuid", value: &uuid.UUID{}, expected: property{Type: "string", Format: "uuid"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &Document{}
+			j.Read(tt.value)
+			if diff := cmp.Diff(tt.expected, j.property); diff != "" {
+				t.Error(diff)
+			}
+
+			deep := &Document{}
+			deep.ReadDeep(tt.value)
+			if diff := cmp.Diff(tt.expected, deep.property); diff != "" {
+				t.Error(diff)
+			}
+
+			defs := &Document{UseDefinitions: true, SharedDateTime: true}
+			defs.Read(tt.value)
+			if diff := cmp.Diff(tt.expected, defs.property); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+type ExampleJSONEmptyStructs struct {
+	Marker  struct{}
+	Pointer *struct{} `json:",omitempty"`
+	Hidden  struct {
+		Secret string `json:"-"`
+	}
+}
+
+func TestEmptyStruct(t *testing.T) {
+	j := &Document{}
+	j.Read(&ExampleJSONEmptyStructs{})
+
+	out, err := json.Marshal(j.Properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"Hidden":{"type":"object"},"Marker":{"type":"object"},"Pointer":{"type":"object"}}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	root := &Document{}
+	root.ReadDeep(struct{}{})
+	if diff := cmp.Diff(property{Type: "object"}, root.property); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("EmptyProperties", func(t *testing.T) {
+		j := &Document{EmptyProperties: true}
+		j.Read(&ExampleJSONEmptyStructs{})
+
+		out, err := json.Marshal(j.Properties)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"Hidden":{"type":"object","properties":{}},"Marker":{"type":"object","properties":{}},"Pointer":{"type":"object","properties":{}}}`
+		if string(out) != expected {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+
+		deep := &Document{EmptyProperties: true}
+		deep.ReadDeep(&ExampleJSONEmptyStructs{})
+		if deep.Properties["Marker"].Properties == nil {
+			t.Error("expected empty properties in deep reads")
+		}
+	})
+	t.Run("EmptyProperties on other objects", func(t *testing.T) {
+		j := &Document{EmptyProperties: true, UseDefinitions: true}
+		j.Read(&struct {
+			Any    map[string]interface{}
+			Lock   sync.Mutex
+			Marker ExampleJSONEmptyStructs
+		}{})
+
+		out, err := json.Marshal(j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"$schema":"http://json-schema.org/schema#","type":"object","properties":{` +
+			`"Any":{"type":"object","properties":{},"additionalProperties":true},` +
+			`"Lock":{"type":"object","properties":{},"additionalProperties":true},` +
+			`"Marker":{"$ref":"#/definitions/ExampleJSONEmptyStructs"}},` +
+			`"required":["Any","Lock","Marker"],` +
+			`"definitions":{"ExampleJSONEmptyStructs":{"type":"object","properties":{` +
+			`"Hidden":{"type":"object","properties":{}},` +
+			`"Marker":{"type":"object","properties":{}},` +
+			`"Pointer":{"type":"object","properties":{}}},"required":["Marker","Hidden"]}}}`
+		if string(out) != expected {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+	})
+}
+
+type ContainsUser struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type ExampleJSONContains struct {
+	Team struct {
+		Members []ContainsUser `json:"members"`
+	} `json:"team"`
+	Name string `json:"name"`
+}
+
+type ContainsTeam struct {
+	Members []ContainsUser `json:"members"`
+}
+
+type ExampleJSONContainsDefinitions struct {
+	Team ContainsTeam `json:"team"`
+}
+
+func TestSetContains(t *testing.T) {
+	admin := &property{
+		Properties: map[string]*property{"role": {Const: "admin"}},
+		Required:   []string{"role"},
+	}
+
+	t.Run("array", func(t *testing.T) {
+		j := &Document{}
+		j.SetContains("team.members", admin)
+		if err := j.TryRead(&ExampleJSONContains{}); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := json.Marshal(j.Properties["team"].Properties["members"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"type":"array","items":{"type":"object","properties":{"name":{"type":"string"},"role":{"type":"string"}},"required":["name","role"]},` +
+			`"contains":{"properties":{"role":{"const":"admin"}},"required":["role"]}}`
+		if string(out) != expected {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+	})
+	t.Run("definitions", func(t *testing.T) {
+		j := &Document{UseDefinitions: true}
+		j.SetContains("team.members", admin)
+		if err := j.TryRead(&ExampleJSONContainsDefinitions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(admin, j.Definitions["ContainsTeam"].Properties["members"].Contains); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("unknown path in strict mode", func(t *testing.T) {
+		j := &Document{Strict: true}
+		j.SetContains("team.admins", admin)
+		err := j.TryRead(&ExampleJSONContains{})
+		if err == nil || err.Error() != "jsonschema: team.admins: no property at the path" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("not an array", func(t *testing.T) {
+		j := &Document{}
+		j.SetContains("name", admin)
+		err := j.TryRead(&ExampleJSONContains{})
+		if err == nil || err.Error() != "jsonschema: name: contains requires an array" {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if j.Properties["name"].Contains != nil {
+			t.Error("contains set on a string")
+		}
+	})
+	t.Run("draft-04", func(t *testing.T) {
+		j := NewDocument("http://json-schema.org/draft-04/schema#")
+		j.SetContains("team.members", admin)
+		err := j.TryRead(&ExampleJSONContains{})
+		if err == nil || err.Error() != "jsonschema: team.members: contains requires draft-06 or later" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestRegisteredSchemasUnchanged(t *testing.T) {
+	registered := func() *property {
+		return &property{
+			Type:     "object",
+			Format:   "custom",
+			Examples: []interface{}{"a", "b"},
+		}
+	}
+	override, contains, dependent := registered(), registered(), registered()
+
+	j := NewDocument(Draft202012)
+	j.DisableFormats = true
+	j.SingleExample = true
+	j.EmptyProperties = true
+	j.Override("team.lead", override)
+	j.SetContains("team.members", contains)
+	j.SetDependentSchema("team", "members", dependent)
+	j.Read(&struct {
+		Team struct {
+			Lead    ContainsUser   `json:"lead"`
+			Members []ContainsUser `json:"members"`
+		} `json:"team"`
+	}{})
+
+	if lead := j.Properties["team"].Properties["lead"]; lead.Format != "" || lead.Example != "a" || lead.Properties == nil {
+		t.Errorf("options not applied to the override: %+v", lead)
+	}
+	for name, p := range map[string]*property{"override": override, "contains": contains, "dependent": dependent} {
+		if diff := cmp.Diff(registered(), p); diff != "" {
+			t.Errorf("%s: %s", name, diff)
+		}
+	}
+}
+
+type ExampleJSONMinContains struct {
+	Members []ContainsUser `json:"members" jsonschema:"minContains=2,maxContains=3"`
+	Admins  []ContainsUser `json:"admins" jsonschema:"minContains=-1"`
+}
+
+func TestMinContains(t *testing.T) {
+	admin := &property{
+		Properties: map[string]*property{"role": {Const: "admin"}},
+		Required:   []string{"role"},
+	}
+
+	j := NewDocument("https://json-schema.org/draft/2020-12/schema")
+	j.SetContains("members", admin)
+	err := j.TryRead(&ExampleJSONMinContains{})
+	if err == nil || err.Error() != `jsonschema: admins: invalid minContains value "-1": must not be negative ([]jsonschema.ContainsUser)` {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	out, err := json.Marshal(j.Properties["members"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(out), `"contains":{"properties":{"role":{"const":"admin"}},"required":["role"]},"minContains":2,"maxContains":3}`) {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	t.Run("without contains", func(t *testing.T) {
+		j := NewDocument("https://json-schema.org/draft/2020-12/schema")
+		j.Read(&ExampleJSONMinContains{})
+
+		out, err := json.Marshal(j.Properties["members"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(out), "Contains") {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+	})
+	t.Run("draft-07", func(t *testing.T) {
+		j := NewDocument("http://json-schema.org/draft-07/schema#")
+		j.SetContains("members", admin)
+		err := j.TryRead(&struct {
+			Members []ContainsUser `json:"members" jsonschema:"minContains=2"`
+		}{})
+		if err == nil || err.Error() != "jsonschema: members: minContains and maxContains require draft 2019-09 or later" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+type ExampleJSONDependentSchemas struct {
+	CreditCard string `json:"creditCard,omitempty"`
+	Billing    struct {
+		Address string `json:"address,omitempty"`
+	} `json:"billing"`
+	Name string `json:"name"`
+}
+
+func TestSetDependentSchema(t *testing.T) {
+	billing := &property{Required: []string{"billing"}}
+
+	t.Run("root", func(t *testing.T) {
+		j := NewDocument("https://json-schema.org/draft/2020-12/schema")
+		j.SetDependentSchema("", "creditCard", billing)
+		if err := j.TryRead(&ExampleJSONDependentSchemas{}); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := json.Marshal(j.DependentSchemas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != `{"creditCard":{"required":["billing"]}}` {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+	})
+	t.Run("nested", func(t *testing.T) {
+		j := NewDocument("https://json-schema.org/draft/2019-09/schema")
+		j.SetDependentSchema("billing", "address", &property{Comment: "verified"})
+		if err := j.TryRead(&ExampleJSONDependentSchemas{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if j.Properties["billing"].DependentSchemas["address"] == nil {
+			t.Error("dependent schema not set")
+		}
+	})
+	t.Run("definitions", func(t *testing.T) {
+		j := NewDocument(Draft202012)
+		j.UseDefinitions = true
+		j.SetDependentSchema("team", "members", billing)
+		if err := j.TryRead(&ExampleJSONContainsDefinitions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(billing, j.Definitions["ContainsTeam"].DependentSchemas["members"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("not an object", func(t *testing.T) {
+		j := NewDocument("https://json-schema.org/draft/2020-12/schema")
+		j.SetDependentSchema("name", "creditCard", billing)
+		err := j.TryRead(&ExampleJSONDependentSchemas{})
+		if err == nil || err.Error() != "jsonschema: name: dependentSchemas requires an object" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("draft-07", func(t *testing.T) {
+		j := NewDocument("http://json-schema.org/draft-07/schema#")
+		j.SetDependentSchema("", "creditCard", billing)
+		err := j.TryRead(&ExampleJSONDependentSchemas{})
+		if err == nil || err.Error() != "jsonschema: dependentSchemas requires draft 2019-09 or later" {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if j.DependentSchemas != nil {
+			t.Error("dependentSchemas set for draft-07")
+		}
+	})
+}
+
+type AliasedTime = time.Time
+
+type FullyQualifiedFormat struct {
+	Value int
+}
+
+type ExampleJSONFormatMapping struct {
+	Aliased AliasedTime
+	Custom  FullyQualifiedFormat
+}
+
+func TestFormatMappingNames(t *testing.T) {
+	fullName := reflect.TypeOf(FullyQualifiedFormat{}).PkgPath() + ".FullyQualifiedFormat"
+	formatMapping[fullName] = []string{"string", "custom"}
+	defer delete(formatMapping, fullName)
+
+	j := &Document{}
+	j.Read(&ExampleJSONFormatMapping{})
+
+	expected := map[string]*property{
+		"Aliased": {Type: "string", Format: "date-time"},
+		"Custom":  {Type: "string", Format: "custom"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type NestedSliceItem struct {
+	Name  string
+	Inner struct {
+		Count int `json:",omitempty"`
+	}
+}
+
+type ExampleJSONNestedSlices struct {
+	SliceOfMaps         []map[string]int
+	SliceOfStructs      []NestedSliceItem
+	SliceOfPointers     []*NestedSliceItem `json:",omitempty"`
+	SliceOfSliceOfInts  [][]int            `json:",omitempty"`
+	SliceOfSliceOfBytes [][]byte           `json:",omitempty"`
+}
+
+func TestLoadNestedSlices(t *testing.T) {
+	j := &Document{}
+	j.Read(&ExampleJSONNestedSlices{})
+
+	item := &property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Name": {Type: "string"},
+			"Inner": {
+				Type: "object",
+				Properties: map[string]*property{
+					"Count": {Type: "integer"},
+				},
+			},
+		},
+		Required: []string{"Name", "Inner"},
+	}
+
+	expected := map[string]*property{
+		"SliceOfMaps": {
+			Type: "array",
+			Items: &property{
+				Type:       "object",
+				Properties: map[string]*property{".*": {Type: "integer"}},
+			},
+		},
+		"SliceOfStructs":  {Type: "array", Items: item},
+		"SliceOfPointers": {Type: "array", Items: item},
+		"SliceOfSliceOfInts": {
+			Type:  "array",
+			Items: &property{Type: "array", Items: &property{Type: "integer"}},
+		},
+		"SliceOfSliceOfBytes": {
+			Type:  "array",
+			Items: &property{Type: "string"},
+		},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func (self *propertySuite) TestLoadAllOptional(c *C) {
+	j := &Document{AllOptional: true}
+	j.Read(&ExampleJSONNestedStruct{})
+
+	c.Assert(j.property, DeepEquals, property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Struct": {
+				Type: "object",
+				Properties: map[string]*property{
+					"Foo": {Type: "string"},
+				},
+			},
+		},
+	})
+
+	json, err := j.Marshal()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(json), "required"), Equals, false)
+}
+
+func (self *propertySuite) TestLoadDeepAllOptional(c *C) {
+	j := &Document{AllOptional: true}
+	j.ReadDeep(&ExampleJSONEmbeddedStruct{})
+
+	c.Assert(j.property, DeepEquals, property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Foo": {Type: "string"},
+		},
+	})
+}
+
+func TestReadDeepAnonymousStruct(t *testing.T) {
+	anonymous := reflect.TypeOf(struct {
+		Name  string
+		Inner struct {
+			Count int `json:"count"`
+		}
+		Any interface{} `json:",omitempty"`
+	}{})
+
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Name": {Type: "string"},
+			"Inner": {
+				Type:       "object",
+				Properties: map[string]*property{"count": {Type: "integer"}},
+				Required:   []string{"count"},
+			},
+			"Any": {
+				Type:       "object",
+				Properties: map[string]*property{"Enabled": {Type: "boolean"}},
+				Required:   []string{"Enabled"},
+			},
+		},
+		Required: []string{"Name", "Inner"},
+	}
+
+	newValue := func() reflect.Value {
+		v := reflect.New(anonymous)
+		v.Elem().Field(0).SetString("example")
+		v.Elem().Field(1).Field(0).SetInt(3)
+		v.Elem().Field(2).Set(reflect.ValueOf(struct{ Enabled bool }{true}))
+		return v
+	}
+
+	t.Run("addressable pointer root", func(t *testing.T) {
+		j := &Document{}
+		j.ReadDeep(newValue().Interface())
+
+		if diff := cmp.Diff(expected, j.property); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("non-addressable value root", func(t *testing.T) {
+		j := &Document{}
+		j.ReadDeep(newValue().Elem().Interface())
+
+		if diff := cmp.Diff(expected, j.property); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("zero value", func(t *testing.T) {
+		j := &Document{}
+		j.ReadDeep(reflect.New(anonymous).Interface())
+
+		zero := expected
+		zero.Properties = map[string]*property{
+			"Name":  expected.Properties["Name"],
+			"Inner": expected.Properties["Inner"],
+			"Any":   {Type: "null"},
+		}
+		if diff := cmp.Diff(zero, j.property); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+type ExampleJSONCustomRequired struct {
+	Name     string
+	Nickname *string
+	Age      int    `json:",omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+func TestCustomIsRequired(t *testing.T) {
+	j := &Document{
+		IsRequired: func(field reflect.StructField, opts TagOptions) bool {
+			return field.Type.Kind() != reflect.Ptr
+		},
+	}
+	j.Read(&ExampleJSONCustomRequired{})
+
+	expected := []string{"Name", "Age", "email"}
+	if diff := cmp.Diff(expected, j.Required); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("AllOptional takes precedence", func(t *testing.T) {
+		j.AllOptional = true
+		j.property = property{}
+		j.Read(&ExampleJSONCustomRequired{})
+
+		if j.Required != nil {
+			t.Errorf("expected no required fields, got %v", j.Required)
+		}
+	})
+}
+
+type ExampleJSONBuiltinFormats struct {
+	Email   mail.Address
+	Website *url.URL
+	ID      uuid.UUID
+	IDs     []uuid.UUID
+}
+
+func TestBuiltinFormats(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONBuiltinFormats{})
+
+		if diff := cmp.Diff(&property{Type: "string", Format: "uuid"}, j.Properties["ID"]); diff != "" {
+			t.Error(diff)
+		}
+		if diff := cmp.Diff(&property{Type: "array", Items: &property{Type: "string", Format: "uuid"}}, j.Properties["IDs"]); diff != "" {
+			t.Error(diff)
+		}
+		// encoding/json encodes these as objects, as they don't marshal
+		// themselves.
+		for _, name := range []string{"Email", "Website"} {
+			if p := j.Properties[name]; p.Type != "object" || p.Format != "" {
+				t.Errorf("%s: expected plain object, got %+v", name, p)
+			}
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		j := &Document{DisableBuiltinFormats: true}
+		j.Read(&ExampleJSONBuiltinFormats{})
+
+		for _, name := range []string{"Email", "Website"} {
+			if p := j.Properties[name]; p.Type != "object" || p.Format != "" {
+				t.Errorf("%s: expected plain object, got %+v", name, p)
+			}
+		}
+		if p := j.Properties["ID"]; p.Format != "" {
+			t.Errorf("ID: expected no format, got %+v", p)
+		}
+	})
+}
+
+func TestDisableFormats(t *testing.T) {
+	type formats struct {
+		ID      uuid.UUID
+		IDs     []uuid.UUID
+		Created time.Time
+		Day     string `jsonschema:"format=date"`
+	}
+
+	j := &Document{DisableFormats: true, UseDefinitions: true, SharedDateTime: true}
+	j.Read(&formats{})
+
+	var formatted []string
+	j.Walk(func(path string, p *property) {
+		if p.Format != "" {
+			formatted = append(formatted, path)
+		}
+	})
+	if len(formatted) != 0 {
+		t.Errorf("unexpected formats at %v", formatted)
+	}
+
+	expected := map[string]*property{
+		"ID":      {Type: "string"},
+		"IDs":     {Type: "array", Items: &property{Type: "string"}},
+		"Created": {Ref: "#/definitions/DateTime"},
+		"Day":     {Type: "string"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(&property{Type: "string"}, j.Definitions["DateTime"]); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{DisableFormats: true}
+	deep.ReadDeep(&formats{})
+	if p := deep.Properties["Created"]; p.Type != "string" || p.Format != "" {
+		t.Errorf("Created: expected plain string, got %+v", p)
+	}
+
+	t.Run("fallbacks", func(t *testing.T) {
+		j := &Document{DisableFormats: true, FormatFallbacks: DefaultFormatFallbacks}
+		j.Read(&struct {
+			Email   string `jsonschema:"format=email"`
+			Work    string `jsonschema:"format=email,pattern=@example\\.com$"`
+			Website string `jsonschema:"format=uri"`
+		}{})
+
+		expected := map[string]*property{
+			"Email":   {Type: "string", Pattern: DefaultFormatFallbacks["email"]},
+			"Work":    {Type: "string", Pattern: `@example\.com$`},
+			"Website": {Type: "string"},
+		}
+		if diff := cmp.Diff(expected, j.Properties); diff != "" {
+			t.Error(diff)
+		}
+
+		validate := j.Validator()
+		if err := validate(map[string]interface{}{"Email": "a@b.co", "Work": "a@example.com", "Website": "x"}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := validate(map[string]interface{}{"Email": "a@b", "Work": "a@example.com", "Website": "x"}); err == nil {
+			t.Error("expected an error for an invalid email")
+		}
+
+		kept := &Document{FormatFallbacks: DefaultFormatFallbacks}
+		kept.Read(&struct {
+			Email string `jsonschema:"format=email"`
+		}{})
+		if diff := cmp.Diff(&property{Type: "string", Format: "email"}, kept.Properties["Email"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+type ExampleJSONOpaque struct {
+	sync.Mutex
+	Cache   sync.Map
+	Counter *atomic.Value `json:",omitempty"`
+	Name    string
+}
+
+func TestOpaqueTypes(t *testing.T) {
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Cache":   {Type: "object", AdditionalProperties: true},
+			"Counter": {Type: "object", AdditionalProperties: true},
+			"Name":    {Type: "string"},
+		},
+		Required: []string{"Cache", "Name"},
+	}
+
+	j := &Document{}
+	if err := j.TryRead(&ExampleJSONOpaque{}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{}
+	if err := deep.TryReadDeep(&ExampleJSONOpaque{Counter: &atomic.Value{}}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, deep.property); diff != "" {
+		t.Error(diff)
+	}
+
+	strict := &Document{Strict: true}
+	err := strict.TryRead(&ExampleJSONOpaque{})
+	if err == nil || err.Error() != "jsonschema: Cache: type can't be serialized (sync.Map)" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	t.Run("unexported fields", func(t *testing.T) {
+		type guarded struct {
+			mu    sync.Mutex
+			state struct{ Counter atomic.Value }
+			Name  string
+		}
+
+		for _, useDefinitions := range []bool{false, true} {
+			j := &Document{Strict: true, UseDefinitions: useDefinitions, CacheTypes: true}
+			if err := j.TryRead(&guarded{}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			deep := &Document{Strict: true}
+			if err := deep.TryReadDeep(&guarded{}); err != nil {
+				t.Errorf("unexpected error in deep read: %v", err)
+			}
+		}
+
+		j := &Document{Strict: true, CacheTypes: true}
+		err := j.TryRead(&struct {
+			mu   sync.Mutex
+			Lock sync.Mutex
+		}{})
+		if err == nil || err.Error() != "jsonschema: Lock: type can't be serialized (sync.Mutex)" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+type StringerStruct struct {
+	Major, Minor int
+}
+
+func (v *StringerStruct) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+type ExampleJSONStringer struct {
+	Version  StringerStruct
+	Previous []*StringerStruct
+	Created  time.Time
+}
+
+func TestStringerAsString(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONStringer{})
+
+		if j.Properties["Version"].Type != "object" {
+			t.Errorf("expected object, got %+v", j.Properties["Version"])
+		}
+	})
+	t.Run("enabled", func(t *testing.T) {
+		j := &Document{StringerAsString: true}
+		j.Read(&ExampleJSONStringer{})
+
+		expected := map[string]*property{
+			"Version":  {Type: "string"},
+			"Previous": {Type: "array", Items: &property{Type: "string"}},
+			"Created":  {Type: "string", Format: "date-time"},
+		}
+		if diff := cmp.Diff(expected, j.Properties); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+func TestLoadArray(t *testing.T) {
+	j := &Document{}
+	j.Read([3]string{})
+
+	three := 3
+	expected := property{
+		Type:     "array",
+		Items:    &property{Type: "string"},
+		MinItems: &three,
+		MaxItems: &three,
+	}
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type ArrayPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type ExampleJSONArrayOfStructs struct {
+	Segment [2]ArrayPoint  `json:"segment"`
+	Corners [4]*ArrayPoint `json:"corners"`
+}
+
+func TestLoadArrayOfStructs(t *testing.T) {
+	point := &property{
+		Type: "object",
+		Properties: map[string]*property{
+			"x": {Type: "number"},
+			"y": {Type: "number"},
+		},
+		Required: []string{"x", "y"},
+	}
+	two, four := 2, 4
+	expected := map[string]*property{
+		"segment": {Type: "array", Items: point, MinItems: &two, MaxItems: &two},
+		"corners": {Type: "array", Items: point, MinItems: &four, MaxItems: &four},
+	}
+
+	j := &Document{}
+	j.Read(&ExampleJSONArrayOfStructs{})
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{}
+	deep.ReadDeep(&ExampleJSONArrayOfStructs{})
+	if diff := cmp.Diff(expected, deep.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("definitions", func(t *testing.T) {
+		j := &Document{UseDefinitions: true}
+		j.Read(&ExampleJSONArrayOfStructs{})
+
+		if diff := cmp.Diff(&property{Ref: "#/definitions/ArrayPoint"}, j.Properties["segment"].Items); diff != "" {
+			t.Error(diff)
+		}
+		if diff := cmp.Diff(point, j.Definitions["ArrayPoint"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+func TestReadTypeAndValue(t *testing.T) {
+	value := map[string]interface{}{
+		"name":  "example",
+		"count": 1,
+	}
+
+	t.Run("ReadValue", func(t *testing.T) {
+		j := &Document{}
+		j.ReadValue(reflect.ValueOf(value))
+
+		expected := &Document{}
+		expected.ReadDeep(value)
+
+		if diff := cmp.Diff(*expected, *j, cmp.AllowUnexported(Document{})); diff != "" {
+			t.Error(diff)
+		}
+		if j.Properties["count"].Type != "integer" {
+			t.Errorf("expected integer, got %+v", j.Properties["count"])
+		}
+	})
+	t.Run("ReadValue of struct field", func(t *testing.T) {
+		v := reflect.ValueOf(ExampleJSONNestedStruct{}).Field(0)
+
+		j := &Document{}
+		j.ReadValue(v)
+
+		if j.Properties["Foo"].Type != "string" {
+			t.Errorf("expected string, got %+v", j.Properties["Foo"])
+		}
+	})
+	t.Run("ReadType", func(t *testing.T) {
+		j := &Document{}
+		j.ReadType(reflect.TypeOf(ExampleJSONBasicWithTag{}))
+
+		expected := &Document{}
+		expected.Read(&ExampleJSONBasicWithTag{})
+
+		if diff := cmp.Diff(*expected, *j, cmp.AllowUnexported(Document{})); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+func TestParseTagOptions(t *testing.T) {
+	tests := []struct {
+		tag       string
+		name      string
+		omitempty bool
+		asString  bool
+	}{
+		{tag: "", name: ""},
+		{tag: "name", name: "name"},
+		{tag: "name,omitempty", name: "name", omitempty: true},
+		{tag: "name,string", name: "name", asString: true},
+		{tag: "name,omitempty,string", name: "name", omitempty: true, asString: true},
+		{tag: "name,string,omitempty", name: "name", omitempty: true, asString: true},
+		{tag: ",omitempty", name: "", omitempty: true},
+		{tag: ",string", name: "", asString: true},
+		{tag: "name,omitempty,", name: "name", omitempty: true},
+		{tag: "name,,omitempty", name: "name", omitempty: true},
+		{tag: "name,", name: "name"},
+		{tag: ",", name: ""},
+		{tag: "name,omitemptyish", name: "name"},
+		{tag: "omitempty", name: "omitempty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			name, opts := parseTag(tt.tag)
+			if name != tt.name {
+				t.Errorf("name: got %q, expected %q", name, tt.name)
+			}
+			if got := opts.Contains("omitempty"); got != tt.omitempty {
+				t.Errorf("omitempty: got %v, expected %v", got, tt.omitempty)
+			}
+			if got := opts.Contains("string"); got != tt.asString {
+				t.Errorf("string: got %v, expected %v", got, tt.asString)
+			}
+			if opts.Contains("") {
+				t.Error("empty option must never match")
+			}
+		})
+	}
+}
+
+type MapUser struct {
+	Name  string
+	Email string `json:",omitempty"`
+}
+
+type ExampleJSONMapOfStructs struct {
+	Users    map[string]MapUser
+	Pointers map[string]*MapUser `json:",omitempty"`
+	Lists    map[string][]int    `json:",omitempty"`
+}
+
+func TestLoadMapOfStructs(t *testing.T) {
+	j := &Document{}
+	j.Read(&ExampleJSONMapOfStructs{})
+
+	user := &property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Name":  {Type: "string"},
+			"Email": {Type: "string"},
+		},
+		Required: []string{"Name"},
+	}
+	expected := map[string]*property{
+		"Users":    {Type: "object", Properties: map[string]*property{".*": user}},
+		"Pointers": {Type: "object", Properties: map[string]*property{".*": user}},
+		"Lists": {
+			Type: "object",
+			Properties: map[string]*property{
+				".*": {Type: "array", Items: &property{Type: "integer"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestMapValueRequired(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		j := &Document{}
+		j.Read(map[string]MapUser{})
+
+		out, err := json.Marshal(j.property)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"type":"object","properties":{".*":{"type":"object","properties":{"Email":{"type":"string"},"Name":{"type":"string"}},"required":["Name"]}}}`
+		if string(out) != expected {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+	})
+	t.Run("deep", func(t *testing.T) {
+		j := &Document{}
+		j.ReadDeep(map[string]*MapUser{"ada": {Name: "Ada"}})
+
+		if diff := cmp.Diff([]string{"Name"}, j.Properties["ada"].Required); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("definitions", func(t *testing.T) {
+		j := &Document{UseDefinitions: true}
+		j.Read(&ExampleJSONMapOfStructs{})
+
+		if diff := cmp.Diff(&property{Ref: "#/definitions/MapUser"}, j.Properties["Users"].Properties[".*"]); diff != "" {
+			t.Error(diff)
+		}
+		if diff := cmp.Diff([]string{"Name"}, j.Definitions["MapUser"].Required); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+func TestLoadNestedMaps(t *testing.T) {
+	j := &Document{}
+	j.Read(map[string]map[string]map[string]int{})
+
+	level := func(value *property) *property {
+		return &property{Type: "object", Properties: map[string]*property{".*": value}}
+	}
+	expected := level(level(level(&property{Type: "integer"})))
+	if diff := cmp.Diff(expected, &j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{}
+	deep.ReadDeep(map[string]map[string]map[string]int{
+		"eu": {"de": {"berlin": 1}},
+	})
+	named := func(name string, value *property) *property {
+		return &property{Type: "object", Properties: map[string]*property{name: value}}
+	}
+	expected = named("eu", named("de", named("berlin", &property{Type: "integer"})))
+	if diff := cmp.Diff(expected, &deep.property); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type Blob []byte
+
+type ExampleJSONNamedBytes struct {
+	Data     Blob
+	Optional *Blob `json:",omitempty"`
+	Chunks   []Blob
+}
+
+func TestLoadNamedByteSlice(t *testing.T) {
+	expected := map[string]*property{
+		"Data":     {Type: "string"},
+		"Optional": {Type: "string"},
+		"Chunks":   {Type: "array", Items: &property{Type: "string"}},
+	}
+
+	j := &Document{}
+	j.Read(&ExampleJSONNamedBytes{})
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	blob := Blob("data")
+	deep := &Document{}
+	deep.ReadDeep(&ExampleJSONNamedBytes{Data: blob, Optional: &blob, Chunks: []Blob{blob}})
+	if diff := cmp.Diff(expected, deep.Properties); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type ExampleJSONOmitemptyNullable struct {
+	Name     string
+	Nickname string   `json:",omitempty"`
+	Age      *int     `json:",omitempty"`
+	Tags     []string `json:",omitempty"`
+}
+
+func TestOmitemptyAsNullable(t *testing.T) {
+	j := &Document{OmitemptyAsNullable: true}
+	j.Read(&ExampleJSONOmitemptyNullable{})
+
+	expected := map[string]*property{
+		"Name":     {Type: "string"},
+		"Nickname": {Type: "string", Nullable: true},
+		"Age":      {Type: "integer", Nullable: true},
+		"Tags":     {Type: "array", Nullable: true, Items: &property{Type: "string"}},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedJSON := `{"Age":{"type":["integer","null"]},"Name":{"type":"string"},` +
+		`"Nickname":{"type":["string","null"]},"Tags":{"type":["array","null"],"items":{"type":"string"}}}`
+	if string(out) != expectedJSON {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	t.Run("deep", func(t *testing.T) {
+		j := &Document{OmitemptyAsNullable: true}
+		j.ReadDeep(&ExampleJSONOmitemptyNullable{})
+
+		if !j.Properties["Nickname"].Nullable || j.Properties["Name"].Nullable {
+			t.Errorf("unexpected nullability: %+v", j.Properties)
+		}
+	})
+	t.Run("references", func(t *testing.T) {
+		j := &Document{OmitemptyAsNullable: true, UseDefinitions: true}
+		j.Read(&struct {
+			Home *DefinitionAddress `json:",omitempty" jsonschema:"description=Where they live"`
+			Work DefinitionAddress
+		}{})
+
+		out, err := json.Marshal(j.Properties)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedJSON := `{"Home":{"anyOf":[{"$ref":"#/definitions/DefinitionAddress","description":"Where they live"},{"type":"null"}]},` +
+			`"Work":{"$ref":"#/definitions/DefinitionAddress"}}`
+		if string(out) != expectedJSON {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+
+		validate := j.Validator()
+		if err := validate(map[string]interface{}{"Home": nil, "Work": map[string]interface{}{"Street": "Main"}}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+type ExampleJSONNumber struct {
+	Amount  json.Number `jsonschema:"default=1.50"`
+	Amounts []json.Number
+}
+
+func TestJSONNumber(t *testing.T) {
+	t.Run("as number", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONNumber{})
+
+		expected := map[string]*property{
+			"Amount":  {Type: "number", Default: json.Number("1.50")},
+			"Amounts": {Type: "array", Items: &property{Type: "number"}},
+		}
+		if diff := cmp.Diff(expected, j.Properties); diff != "" {
+			t.Error(diff)
+		}
+
+		out, err := json.Marshal(j.Properties["Amount"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != `{"type":"number","default":1.50}` {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+	})
+	t.Run("as string", func(t *testing.T) {
+		j := &Document{JSONNumberAsString: true}
+		j.Read(&ExampleJSONNumber{})
+
+		if j.Properties["Amount"].Type != "string" || j.Properties["Amounts"].Items.Type != "string" {
+			t.Errorf("expected strings, got %+v", j.Properties)
+		}
+	})
+	t.Run("invalid default", func(t *testing.T) {
+		j := &Document{}
+		err := j.TryRead(&struct {
+			Amount json.Number `jsonschema:"default=lots"`
+		}{})
+		if err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestLoadSliceDeepWithNils(t *testing.T) {
+	one := 1
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected *property
+	}{
+		{
+			name:     "nil before value",
+			value:    []interface{}{nil, 1},
+			expected: &property{Type: "array", Items: &property{Type: "integer"}},
+		},
+		{
+			name:     "all nil interfaces",
+			value:    []interface{}{nil, nil},
+			expected: &property{Type: "array"},
+		},
+		{
+			name:     "nil pointer before value",
+			value:    []*int{nil, &one},
+			expected: &property{Type: "array", Items: &property{Type: "integer"}},
+		},
+		{
+			name:     "all nil pointers",
+			value:    []*int{nil},
+			expected: &property{Type: "array", Items: &property{Type: "integer"}},
+		},
+		{
+			name:     "nil maps",
+			value:    []map[string]int{nil, {"a": 1}},
+			expected: &property{Type: "array", Items: &property{Type: "object", Properties: map[string]*property{"a": {Type: "integer"}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &Document{}
+			j.ReadDeep(map[string]interface{}{"list": tt.value})
+
+			if diff := cmp.Diff(tt.expected, j.Properties["list"]); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+type ExampleJSONPointerToInterface struct {
+	Payload *interface{}
+}
+
+func TestReadDeepPointerToInterface(t *testing.T) {
+	var payload interface{} = struct {
+		ID int `json:"id"`
+	}{ID: 1}
+	var empty interface{}
+
+	tests := []struct {
+		name     string
+		value    ExampleJSONPointerToInterface
+		expected *property
+	}{
+		{
+			name:  "holding a struct",
+			value: ExampleJSONPointerToInterface{Payload: &payload},
+			expected: &property{
+				Type:       "object",
+				Properties: map[string]*property{"id": {Type: "integer"}},
+				Required:   []string{"id"},
+			},
+		},
+		{
+			name:     "holding nil",
+			value:    ExampleJSONPointerToInterface{Payload: &empty},
+			expected: &property{Type: "null"},
+		},
+		{
+			name:     "nil pointer",
+			value:    ExampleJSONPointerToInterface{},
+			expected: &property{Type: "null"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &Document{}
+			j.ReadDeep(&tt.value)
+
+			if diff := cmp.Diff(tt.expected, j.Properties["Payload"]); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+type StreamUser struct {
+	Name string
+}
+
+type ExampleJSONChannels struct {
+	Users chan StreamUser
+	Ticks <-chan int `json:",omitempty"`
+}
+
+func TestChannelsAsStreams(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		j := &Document{}
+		err := j.TryRead(&ExampleJSONChannels{})
+
+		var genErr *GenerationError
+		if !errors.As(err, &genErr) || genErr.Path != "Users" {
+			t.Errorf("expected an unsupported type error for Users, got %v", err)
+		}
+	})
+	t.Run("enabled", func(t *testing.T) {
+		j := &Document{ChannelsAsStreams: true}
+		if err := j.TryRead(&ExampleJSONChannels{}); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]*property{
+			"Users": {
+				Type:       "object",
+				Properties: map[string]*property{"Name": {Type: "string"}},
+				Required:   []string{"Name"},
+				Extensions: map[string]interface{}{"x-stream": true},
+			},
+			"Ticks": {
+				Type:       "integer",
+				Extensions: map[string]interface{}{"x-stream": true},
+			},
+		}
+		if diff := cmp.Diff(expected, j.Properties); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("deep", func(t *testing.T) {
+		j := &Document{ChannelsAsStreams: true}
+		if err := j.TryReadDeep(&ExampleJSONChannels{Users: make(chan StreamUser)}); err != nil {
+			t.Fatal(err)
+		}
+
+		if j.Properties["Users"].Type != "object" || j.Properties["Users"].Extensions["x-stream"] != true {
+			t.Errorf("unexpected schema %+v", j.Properties["Users"])
+		}
+	})
+}
+
+type ExampleJSONWriteOnly struct {
+	Username     string
+	Password     string
+	APIToken     string `json:"api_token"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+}
+
+func TestWriteOnlyPattern(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONWriteOnly{})
+
+		for name, p := range j.Properties {
+			if p.WriteOnly {
+				t.Errorf("%s: unexpected writeOnly", name)
+			}
+		}
+	})
+	t.Run("default pattern", func(t *testing.T) {
+		j := &Document{WriteOnlyPattern: DefaultWriteOnlyPattern}
+		j.Read(&ExampleJSONWriteOnly{})
+
+		expected := map[string]*property{
+			"Username":     {Type: "string"},
+			"Password":     {Type: "string", WriteOnly: true},
+			"api_token":    {Type: "string", WriteOnly: true},
+			"clientSecret": {Type: "string", WriteOnly: true},
+		}
+		if diff := cmp.Diff(expected, j.Properties); diff != "" {
+			t.Error(diff)
+		}
+
+		out, err := json.Marshal(j.Properties["Password"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != `{"type":"string","writeOnly":true}` {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+	})
+	t.Run("custom pattern", func(t *testing.T) {
+		j := &Document{WriteOnlyPattern: regexp.MustCompile(`^Username$`)}
+		j.ReadDeep(&ExampleJSONWriteOnly{})
+
+		if !j.Properties["Username"].WriteOnly || j.Properties["Password"].WriteOnly {
+			t.Errorf("unexpected writeOnly flags: %+v", j.Properties)
+		}
+	})
+}
+
+type CollisionBase struct {
+	ID   string
+	Name string
+}
+
+type ExampleJSONCollision struct {
+	CollisionBase
+	Name string
+}
+
+func TestStrictCollisions(t *testing.T) {
+	t.Run("lenient", func(t *testing.T) {
+		j := &Document{}
+		if err := j.TryRead(&ExampleJSONCollision{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("embedded and outer field", func(t *testing.T) {
+		j := &Document{Strict: true}
+		err := j.TryRead(&ExampleJSONCollision{})
+
+		var genErr *GenerationError
+		if !errors.As(err, &genErr) {
+			t.Fatalf("expected a GenerationError, got %v", err)
+		}
+		expected := `property "Name" is defined by both CollisionBase.Name and Name`
+		if genErr.Reason != expected {
+			t.Errorf("unexpected reason %q", genErr.Reason)
+		}
+	})
+	t.Run("deep", func(t *testing.T) {
+		j := &Document{Strict: true}
+		if err := j.TryReadDeep(&ExampleJSONCollision{}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+	t.Run("embedded structs with definitions", func(t *testing.T) {
+		j := &Document{UseDefinitions: true}
+		j.Read(&struct {
+			Outer ExampleJSONEmbeddedStruct
+		}{})
+
+		outer := j.Definitions["ExampleJSONEmbeddedStruct"]
+		if outer == nil || outer.Properties["Foo"] == nil {
+			t.Errorf("expected embedded fields to be promoted, got %+v", outer)
+		}
+	})
+}
+
+type EmptyBase struct{}
+
+type ExampleJSONEmptyEmbedding struct {
+	EmptyBase
+	Name string
+}
+
+type HiddenBase struct{ id int }
+
+type ExampleJSONHiddenEmbedding struct {
+	HiddenBase
+	Name string
+}
+
+func TestStrictEmptyEmbedding(t *testing.T) {
+	lenient := &Document{}
+	if err := lenient.TryRead(&ExampleJSONEmptyEmbedding{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		read func(j *Document) error
+	}{
+		{"read", func(j *Document) error { return j.TryRead(&ExampleJSONEmptyEmbedding{}) }},
+		{"deep", func(j *Document) error { return j.TryReadDeep(&ExampleJSONEmptyEmbedding{}) }},
+		{"allOf", func(j *Document) error {
+			j.UseDefinitions, j.EmbedAsAllOf = true, true
+			return j.TryRead(&ExampleJSONEmptyEmbedding{})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.read(&Document{Strict: true})
+
+			var genErr *GenerationError
+			if !errors.As(err, &genErr) {
+				t.Fatalf("expected a GenerationError, got %v", err)
+			}
+			if genErr.Reason != "embedded EmptyBase promotes no properties" {
+				t.Errorf("unexpected reason %q", genErr.Reason)
+			}
+		})
+	}
+
+	t.Run("only unexported fields", func(t *testing.T) {
+		for _, read := range []func(j *Document) error{
+			func(j *Document) error { return j.TryRead(&ExampleJSONHiddenEmbedding{}) },
+			func(j *Document) error { return j.TryReadDeep(&ExampleJSONHiddenEmbedding{}) },
+		} {
+			j := &Document{Strict: true}
+			err := read(j)
+			if err == nil || err.Error() != "jsonschema: embedded HiddenBase promotes no properties (jsonschema.ExampleJSONHiddenEmbedding)" {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if _, ok := j.Properties["id"]; ok {
+				t.Error("unexpected property for the unexported field id")
+			}
+		}
+	})
+	t.Run("embedding with fields", func(t *testing.T) {
+		j := &Document{Strict: true}
+		if err := j.TryRead(&ExampleJSONEmbeddedStruct{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+type ExampleJSONInferExamples struct {
+	Name    string
+	Age     int
+	Score   float64
+	Active  bool
+	Joined  time.Time
+	Balance json.Number
+	Tags    []string
+	Address struct {
+		City string
+	}
+	Nickname *string
+}
+
+func TestInferExamples(t *testing.T) {
+	nickname := "ada"
+	value := &ExampleJSONInferExamples{
+		Name:     "Ada",
+		Age:      36,
+		Score:    9.5,
+		Active:   true,
+		Joined:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Balance:  "12.50",
+		Tags:     []string{"math"},
+		Nickname: &nickname,
+	}
+	value.Address.City = "London"
+
+	j := &Document{InferExamples: true}
+	if err := j.TryReadDeep(value); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := json.Marshal(j.Properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"Active":{"type":"boolean","examples":[true]},` +
+		`"Address":{"type":"object","properties":{"City":{"type":"string","examples":["London"]}},"required":["City"]},` +
+		`"Age":{"type":"integer","examples":[36]},` +
+		`"Balance":{"type":"number","examples":[12.50]},` +
+		`"Joined":{"type":"string","format":"date-time","examples":["2020-01-02T03:04:05Z"]},` +
+		`"Name":{"type":"string","examples":["Ada"]},` +
+		`"Nickname":{"type":"string","examples":["ada"]},` +
+		`"Score":{"type":"number","examples":[9.5]},` +
+		`"Tags":{"type":"array","items":{"type":"string","examples":["math"]}}}`
+	if string(out) != expected {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	plain := &Document{}
+	plain.ReadDeep(value)
+	if plain.Properties["Name"].Examples != nil {
+		t.Error("examples must only be inferred when enabled")
+	}
+}
+
+type ExampleJSONSingleExample struct {
+	Mode  string `json:"mode" jsonschema:"examples=fast|slow"`
+	Plain string `json:"plain"`
+}
+
+func TestSingleExample(t *testing.T) {
+	j := &Document{SingleExample: true, RefPrefix: "#/components/schemas/"}
+	if err := j.TryRead(&ExampleJSONSingleExample{}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := json.Marshal(j.Properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"mode":{"type":"string","example":"fast"},"plain":{"type":"string"}}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	deep := &Document{SingleExample: true, InferExamples: true}
+	deep.ReadDeep(&ExampleJSONSingleExample{Mode: "slow", Plain: "a"})
+	if p := deep.Properties["plain"]; p.Example != "a" || p.Examples != nil {
+		t.Errorf("expected a single inferred example, got %+v", p)
+	}
+
+	plain := &Document{}
+	plain.Read(&ExampleJSONSingleExample{})
+	if p := plain.Properties["mode"]; p.Example != nil || len(p.Examples) != 2 {
+		t.Errorf("expected examples to be kept, got %+v", p)
+	}
+}
+
+type ExampleJSONOptionalNested struct {
+	ID      string
+	Billing ExampleJSONBillingAddress `json:"billing,omitempty"`
+}
+
+type ExampleJSONBillingAddress struct {
+	Street string `json:"street"`
+	Zip    string `json:"zip"`
+	Note   string `json:"note,omitempty"`
+}
+
+func TestOptionalNestedStruct(t *testing.T) {
+	expected := &property{
+		Type: "object",
+		Properties: map[string]*property{
+			"ID": {Type: "string"},
+			"billing": {
+				Type: "object",
+				Properties: map[string]*property{
+					"street": {Type: "string"},
+					"zip":    {Type: "string"},
+					"note":   {Type: "string"},
+				},
+				Required: []string{"street", "zip"},
+			},
+		},
+		Required: []string{"ID"},
+	}
+
+	j := &Document{}
+	j.Read(&ExampleJSONOptionalNested{})
+	if diff := cmp.Diff(expected, &j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{}
+	deep.ReadDeep(&ExampleJSONOptionalNested{})
+	if diff := cmp.Diff(expected, &deep.property); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type ExampleJSONNilPointers struct {
+	Count   *int
+	Address *struct {
+		City string
+	}
+	Set *int
+}
+
+func TestInferNilPointerTypes(t *testing.T) {
+	one := 1
+	value := &ExampleJSONNilPointers{Set: &one}
+
+	j := &Document{}
+	j.ReadDeep(value)
+	if j.Properties["Count"].Type != "null" {
+		t.Errorf("expected null by default, got %q", j.Properties["Count"].Type)
+	}
+
+	inferred := &Document{InferNilPointerTypes: true}
+	inferred.ReadDeep(value)
+
+	expected := map[string]*property{
+		"Count": {Type: "integer"},
+		"Address": {
+			Type:       "object",
+			Properties: map[string]*property{"City": {Type: "string"}},
+			Required:   []string{"City"},
+		},
+		"Set": {Type: "integer"},
+	}
+	if diff := cmp.Diff(expected, inferred.Properties); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type Describer interface {
+	Describe() string
+}
+
+type ExampleJSONEmbeddedInterface struct {
+	Describer
+	fmt.Stringer `json:"label,omitempty"`
+	Name         string
+}
+
+type namedDescription string
+
+func (n namedDescription) Describe() string { return string(n) }
+
+func TestEmbeddedInterface(t *testing.T) {
+	j := &Document{}
+	j.Read(&ExampleJSONEmbeddedInterface{})
+
+	expected := &property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Describer": {},
+			"label":     {},
+			"Name":      {Type: "string"},
+		},
+		Required: []string{"Describer", "Name"},
+	}
+	if diff := cmp.Diff(expected, &j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties["Describer"])
+	if err != nil || string(out) != "{}" {
+		t.Errorf("expected an empty schema, got %s (%v)", out, err)
+	}
+
+	t.Run("deep", func(t *testing.T) {
+		j := &Document{}
+		j.ReadDeep(&ExampleJSONEmbeddedInterface{Describer: namedDescription("widget")})
+
+		if diff := cmp.Diff(&property{Type: "string"}, j.Properties["Describer"]); diff != "" {
+			t.Error(diff)
+		}
+		if diff := cmp.Diff(&property{Type: "null"}, j.Properties["label"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+type ExampleJSONDashTags struct {
+	Skipped string `json:"-"`
+	Dash    string `json:"-,"`
+}
+
+func TestDashTags(t *testing.T) {
+	value := ExampleJSONDashTags{Skipped: "a", Dash: "b"}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != `{"-":"b"}` {
+		t.Fatalf("unexpected encoding/json behaviour: %s", encoded)
+	}
+
+	expected := property{
+		Type:       "object",
+		Properties: map[string]*property{"-": {Type: "string"}},
+		Required:   []string{"-"},
+	}
+
+	j := &Document{}
+	j.Read(&ExampleJSONDashTags{})
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{}
+	deep.ReadDeep(&value)
+	if diff := cmp.Diff(expected, deep.property); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type ExampleJSONCatchAll struct {
+	Name  string                 `json:"name"`
+	Extra map[string]interface{} `json:"-"`
+}
+
+type ExampleJSONHiddenNonMap struct {
+	Name  string   `json:"name"`
+	Cache []string `json:"-"`
+}
+
+func TestCatchAllMap(t *testing.T) {
+	expected := property{
+		Type:                 "object",
+		Properties:           map[string]*property{"name": {Type: "string"}},
+		Required:             []string{"name"},
+		AdditionalProperties: true,
+	}
+
+	j := &Document{}
+	j.Read(&ExampleJSONCatchAll{})
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{}
+	deep.ReadDeep(&ExampleJSONCatchAll{Extra: map[string]interface{}{"a": 1}})
+	if diff := cmp.Diff(expected, deep.property); diff != "" {
+		t.Error(diff)
+	}
+
+	closed := &Document{}
+	closed.Read(&ExampleJSONHiddenNonMap{})
+	if closed.AdditionalProperties {
+		t.Error("expected a hidden slice to leave the object closed")
+	}
+}
+
+type ExampleJSONIntegerBounds struct {
+	Small  uint8
+	Medium int16
+	Large  uint32
+	Huge   uint64
+	Ratio  float64
+}
+
+func TestInferIntegerBounds(t *testing.T) {
+	j := &Document{InferIntegerBounds: true}
+	j.Read(&ExampleJSONIntegerBounds{})
+
+	expected := map[string]*property{
+		"Small":  {Type: "integer", Minimum: uint64(0), Maximum: uint64(255)},
+		"Medium": {Type: "integer", Minimum: int64(-32768), Maximum: int64(32767)},
+		"Large":  {Type: "integer", Minimum: uint64(0), Maximum: uint64(4294967295)},
+		"Huge":   {Type: "integer", Minimum: uint64(0), Maximum: uint64(math.MaxUint64)},
+		"Ratio":  {Type: "number"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties["Medium"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":"integer","minimum":-32768,"maximum":32767}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	deep := &Document{InferIntegerBounds: true}
+	deep.ReadDeep(&ExampleJSONIntegerBounds{})
+	if diff := cmp.Diff(expected, deep.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	plain := &Document{}
+	plain.Read(&ExampleJSONIntegerBounds{})
+	if plain.Properties["Small"].Maximum != nil {
+		t.Errorf("unexpected maximum %v", plain.Properties["Small"].Maximum)
+	}
+}
+
+type ExampleJSONLoose struct {
+	Name    string
+	Created time.Time
+	Address struct {
+		Street string
+	}
+}
+
+func TestSetLoose(t *testing.T) {
+	j := &Document{}
+	j.SetLoose()
+	if !j.AllOptional || !j.AllOpen || !j.DisableFormats {
+		t.Errorf("unexpected flags: %+v", j)
+	}
+	j.Read(&ExampleJSONLoose{})
+
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"Name":    {Type: "string"},
+			"Created": {Type: "string"},
+			"Address": {
+				Type:                 "object",
+				Properties:           map[string]*property{"Street": {Type: "string"}},
+				AdditionalProperties: true,
+			},
+		},
+		AdditionalProperties: true,
+	}
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{}
+	deep.SetLoose()
+	deep.ReadDeep(&ExampleJSONLoose{})
+	if diff := cmp.Diff(expected, deep.property); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type NamedEmbedBase struct {
+	ID string `json:"id"`
+}
+
+type ExampleJSONNamedEmbedPointer struct {
+	*NamedEmbedBase `json:"base"`
+	NamedEmbedded   `json:"value,omitempty"`
+	Name            string `json:"name"`
+}
+
+type NamedEmbedded struct {
+	Value int
+}
+
+func TestNamedEmbeddedPointer(t *testing.T) {
+	value := ExampleJSONNamedEmbedPointer{NamedEmbedBase: &NamedEmbedBase{ID: "a"}, Name: "b"}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != `{"base":{"id":"a"},"value":{"Value":0},"name":"b"}` {
+		t.Fatalf("unexpected encoding/json behaviour: %s", encoded)
+	}
+
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"base": {
+				Type:       "object",
+				Properties: map[string]*property{"id": {Type: "string"}},
+				Required:   []string{"id"},
+			},
+			"value": {
+				Type:       "object",
+				Properties: map[string]*property{"Value": {Type: "integer"}},
+				Required:   []string{"Value"},
+			},
+			"name": {Type: "string"},
+		},
+		Required: []string{"base", "name"},
+	}
+
+	j := &Document{}
+	j.Read(&ExampleJSONNamedEmbedPointer{})
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{}
+	deep.ReadDeep(&value)
+	if diff := cmp.Diff(expected, deep.property); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("definitions", func(t *testing.T) {
+		j := &Document{UseDefinitions: true}
+		j.Read(&ExampleJSONNamedEmbedPointer{})
+
+		if diff := cmp.Diff(&property{Ref: "#/definitions/NamedEmbedBase"}, j.Properties["base"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+type ExampleJSONKeyFormats struct {
+	Owners map[uuid.UUID]string
+	Names  map[string]string
+}
+
+func TestMapKeyFormats(t *testing.T) {
+	j := &Document{}
+	j.Read(&ExampleJSONKeyFormats{})
+
+	expected := map[string]*property{
+		"Owners": {
+			Type:          "object",
+			Properties:    map[string]*property{".*": {Type: "string"}},
+			PropertyNames: &property{Type: "string", Format: "uuid"},
+		},
+		"Names": {
+			Type:       "object",
+			Properties: map[string]*property{".*": {Type: "string"}},
+		},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties["Owners"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"propertyNames":{"type":"string","format":"uuid"}`) {
+		t.Errorf("expected propertyNames in %s", out)
+	}
+
+	t.Run("draft-04", func(t *testing.T) {
+		for _, cache := range []bool{false, true} {
+			cached := &Document{CacheTypes: cache}
+			cached.Read(&ExampleJSONKeyFormats{})
+
+			j := &Document{Schema: Draft04, CacheTypes: cache}
+			j.Read(&ExampleJSONKeyFormats{})
+			if p := j.Properties["Owners"].PropertyNames; p != nil {
+				t.Errorf("expected no propertyNames, got %+v", p)
+			}
+		}
+	})
+}
+
+type ExampleJSONRawMessage struct {
+	Payload json.RawMessage            `json:"payload"`
+	Extra   map[string]json.RawMessage `json:"extra"`
+	Bytes   []byte                     `json:"bytes"`
+}
+
+func TestRawMessage(t *testing.T) {
+	expected := map[string]*property{
+		"payload": {},
+		"extra":   {Type: "object", AdditionalProperties: true},
+		"bytes":   {Type: "string"},
+	}
+
+	j := &Document{}
+	j.Read(&ExampleJSONRawMessage{})
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties["extra"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":"object","additionalProperties":true}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	deep := &Document{}
+	deep.ReadDeep(&ExampleJSONRawMessage{
+		Payload: json.RawMessage(`{"a":1}`),
+		Extra:   map[string]json.RawMessage{"a": json.RawMessage(`[1]`)},
+		Bytes:   []byte("a"),
+	})
+	expected["extra"] = &property{Type: "object", Properties: map[string]*property{"a": {}}}
+	if diff := cmp.Diff(expected, deep.Properties); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type ExampleJSONByteArrays struct {
+	Hash  [32]byte `json:"hash"`
+	Short [2]byte  `json:"short"`
+	Body  []byte   `json:"body"`
+}
+
+func TestByteArraysAsBase64(t *testing.T) {
+	length, short := 44, 4
+	expected := map[string]*property{
+		"hash":  {Type: "string", ContentEncoding: "base64", MinLength: &length, MaxLength: &length},
+		"short": {Type: "string", ContentEncoding: "base64", MinLength: &short, MaxLength: &short},
+		"body":  {Type: "string"},
+	}
+
+	j := &Document{ByteArraysAsBase64: true}
+	j.Read(&ExampleJSONByteArrays{})
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{ByteArraysAsBase64: true}
+	deep.ReadDeep(&ExampleJSONByteArrays{})
+	if diff := cmp.Diff(expected, deep.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties["hash"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":"string","contentEncoding":"base64","minLength":44,"maxLength":44}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONByteArrays{})
+		if p := j.Properties["hash"]; p.Type != "array" || *p.MaxItems != 32 {
+			t.Errorf("expected an array of 32 items, got %+v", p)
+		}
+	})
+}
+
+type ExampleJSONTypedMaps struct {
+	Scores map[string]float64           `json:"scores"`
+	Users  map[string]DefinitionAddress `json:"users"`
+	Any    map[string]interface{}       `json:"any"`
+}
+
+func TestTypedMapsAsAdditionalProperties(t *testing.T) {
+	address := &property{Type: "object", Properties: map[string]*property{"Street": {Type: "string"}}, Required: []string{"Street"}}
+
+	t.Run("pattern property", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONTypedMaps{})
+
+		expected := map[string]*property{
+			"scores": {Type: "object", Properties: map[string]*property{".*": {Type: "number"}}},
+			"users":  {Type: "object", Properties: map[string]*property{".*": address}},
+			"any":    {Type: "object", AdditionalProperties: true},
+		}
+		if diff := cmp.Diff(expected, j.Properties); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("additionalProperties", func(t *testing.T) {
+		j := &Document{TypedMapsAsAdditionalProperties: true}
+		j.Read(&ExampleJSONTypedMaps{})
+
+		expected := map[string]*property{
+			"scores": {Type: "object", AdditionalSchema: &property{Type: "number"}},
+			"users":  {Type: "object", AdditionalSchema: address},
+			"any":    {Type: "object", AdditionalProperties: true},
+		}
+		if diff := cmp.Diff(expected, j.Properties); diff != "" {
+			t.Error(diff)
+		}
+
+		out, err := json.Marshal(j.Properties)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedJSON := `{"any":{"type":"object","additionalProperties":true},` +
+			`"scores":{"type":"object","additionalProperties":{"type":"number"}},` +
+			`"users":{"type":"object","additionalProperties":{"type":"object","properties":{"Street":{"type":"string"}},"required":["Street"]}}}`
+		if string(out) != expectedJSON {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+
+		validate := j.Validator()
+		value := map[string]interface{}{"scores": map[string]interface{}{"a": "high"}, "users": map[string]interface{}{}, "any": map[string]interface{}{}}
+		if err := validate(value); err == nil || err.Error() != "jsonschema: scores.a: expected number, got string" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+type ExampleJSONInterfaceMap struct {
+	Values map[string]interface{} `json:"values"`
+}
+
+func TestInterfaceMapPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		read     func(j *Document)
+		expected *property
+	}{
+		{
+			name:     "read",
+			read:     func(j *Document) { j.Read(&ExampleJSONInterfaceMap{}) },
+			expected: &property{Type: "object", AdditionalProperties: true},
+		},
+		{
+			name:     "deep nil",
+			read:     func(j *Document) { j.ReadDeep(&ExampleJSONInterfaceMap{}) },
+			expected: &property{Type: "object", AdditionalProperties: true},
+		},
+		{
+			name:     "deep empty",
+			read:     func(j *Document) { j.ReadDeep(&ExampleJSONInterfaceMap{Values: map[string]interface{}{}}) },
+			expected: &property{Type: "object", AdditionalProperties: true},
+		},
+		{
+			name: "deep populated",
+			read: func(j *Document) {
+				j.ReadDeep(&ExampleJSONInterfaceMap{Values: map[string]interface{}{"count": 1, "name": "a"}})
+			},
+			expected: &property{Type: "object", Properties: map[string]*property{"count": {Type: "integer"}, "name": {Type: "string"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &Document{}
+			tt.read(j)
+			if diff := cmp.Diff(tt.expected, j.Properties["values"]); diff != "" {
+				t.Error(diff)
+			}
+
+			// Leaving out additionalProperties allows them as well, so every
+			// schema accepts members it doesn't describe.
+			value := map[string]interface{}{"values": map[string]interface{}{"other": []interface{}{1, "a"}, "name": "b"}}
+			if err := j.Validator()(value); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("empty maps read like Read", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONInterfaceMap{})
+		for _, v := range []*ExampleJSONInterfaceMap{{}, {Values: map[string]interface{}{}}} {
+			deep := &Document{}
+			deep.ReadDeep(v)
+			if diff := cmp.Diff(j.String(), deep.String()); diff != "" {
+				t.Error(diff)
+			}
+		}
+	})
+}
+
+type EmbedLevelC struct {
+	Deepest  string `json:"deepest"`
+	Optional string `json:"optional,omitempty"`
+}
+
+type EmbedLevelB struct {
+	*EmbedLevelC
+	Middle int `json:"middle"`
+}
+
+type EmbedLevelA struct {
+	EmbedLevelB
+	Upper bool `json:"upper"`
+}
+
+type ExampleJSONEmbedChain struct {
+	EmbedLevelA
+	Outer string `json:"outer"`
+}
+
+func TestEmbedChain(t *testing.T) {
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"deepest":  {Type: "string"},
+			"optional": {Type: "string"},
+			"middle":   {Type: "integer"},
+			"upper":    {Type: "boolean"},
+			"outer":    {Type: "string"},
+		},
+		Required: []string{"deepest", "middle", "upper", "outer"},
+	}
+
+	j := &Document{Strict: true}
+	if err := j.TryRead(&ExampleJSONEmbedChain{}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, value := range []*ExampleJSONEmbedChain{
+		{EmbedLevelA: EmbedLevelA{EmbedLevelB: EmbedLevelB{EmbedLevelC: &EmbedLevelC{}}}},
+		{},
+	} {
+		deep := &Document{Strict: true}
+		if err := deep.TryReadDeep(value); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, deep.property); diff != "" {
+			t.Error(diff)
+		}
+	}
+
+	ordered := &Document{}
+	ordered.PropertyOrder("ExampleJSONEmbedChain", nil)
+	ordered.Read(&ExampleJSONEmbedChain{})
+	if diff := cmp.Diff([]string{"deepest", "optional", "middle", "upper", "outer"}, ordered.Order); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,411 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// keywordOrder lists the keywords that are emitted first, in this order,
+// so that a schema reads from its identity and type down to its members:
+// "$schema", "$id", "$ref", "title", "description", "type", "format",
+// "enum", "properties", "required" and "additionalProperties". The other
+// keywords follow in their declaration order in property, and extensions
+// come last.
+var keywordOrder = []string{
+	"$schema",
+	"$id",
+	"$ref",
+	"title",
+	"description",
+	"type",
+	"format",
+	"enum",
+	"properties",
+	"required",
+	"additionalProperties",
+}
+
+var keywordRanks = func() map[string]int {
+	ranks := make(map[string]int, len(keywordOrder))
+	for i, keyword := range keywordOrder {
+		ranks[keyword] = i
+	}
+	return ranks
+}()
+
+// propertyJSON has the fields of property without its MarshalJSON method,
+// so that the default encoding can be reused.
+type propertyJSON property
+
+// MarshalJSON encodes the property, emitting tuple items as an array under
+// "items", or "prefixItems" for draft 2020-12, and the type of a nullable property as a list including "null",
+// which is then added to its enum as well. A nullable property without a
+// type, such as a reference, becomes an anyOf of its schema and null.
+// Extensions follow the standard keywords, sorted by key, except for
+// "$schema".
+func (p property) MarshalJSON() ([]byte, error) {
+	if p.isNullableSchema() {
+		schema := p
+		schema.Nullable = false
+		return json.Marshal(property{AnyOf: []*property{&schema, {Type: "null"}}})
+	}
+
+	typ := p.encodedType()
+	if _, ok := typ.([]string); ok {
+		if p.Enum != nil && !containsNil(p.Enum) {
+			p.Enum = append(append(make([]interface{}, 0, len(p.Enum)+1), p.Enum...), nil)
+		}
+	}
+
+	// minContains and maxContains have no meaning without contains.
+	if p.Contains == nil {
+		p.MinContains, p.MaxContains = nil, nil
+	}
+
+	// additionalProperties is either a schema or true.
+	var additional interface{}
+	switch {
+	case p.AdditionalSchema != nil:
+		additional = p.AdditionalSchema
+	case p.AdditionalProperties:
+		additional = true
+	}
+
+	// An empty but non-nil Properties is kept, see EmptyProperties.
+	var properties interface{}
+	switch {
+	case p.Order != nil && p.Properties != nil:
+		properties = orderedProperties{p.Properties, p.Order}
+	case p.Properties != nil:
+		properties = &p.Properties
+	}
+
+	var body []byte
+	var err error
+	switch {
+	case p.TupleItems == nil:
+		body, err = json.Marshal(struct {
+			Type       interface{} `json:"type,omitempty"`
+			Properties interface{} `json:"properties,omitempty"`
+			Additional interface{} `json:"additionalProperties,omitempty"`
+			propertyJSON
+		}{typ, properties, additional, propertyJSON(p)})
+	case p.PrefixItems:
+		// items applies to the items after prefixItems, like
+		// additionalItems does after the array form of items.
+		items := p.AdditionalItems
+		p.AdditionalItems = nil
+		body, err = json.Marshal(struct {
+			Type       interface{} `json:"type,omitempty"`
+			Properties interface{} `json:"properties,omitempty"`
+			Additional interface{} `json:"additionalProperties,omitempty"`
+			propertyJSON
+			PrefixItems []*property `json:"prefixItems"`
+			Items       *bool       `json:"items,omitempty"`
+		}{typ, properties, additional, propertyJSON(p), p.TupleItems, items})
+	default:
+		body, err = json.Marshal(struct {
+			Type       interface{} `json:"type,omitempty"`
+			Properties interface{} `json:"properties,omitempty"`
+			Additional interface{} `json:"additionalProperties,omitempty"`
+			propertyJSON
+			Items []*property `json:"items"`
+		}{typ, properties, additional, propertyJSON(p), p.TupleItems})
+	}
+	if err != nil {
+		return nil, err
+	}
+	extensions := p.Extensions
+	if _, ok := extensions["$schema"]; ok {
+		// Only the Document emits $schema, so that definitions and bundled
+		// Documents never declare a dialect of their own.
+		extensions = make(map[string]interface{}, len(p.Extensions))
+		for key, value := range p.Extensions {
+			if key != "$schema" {
+				extensions[key] = value
+			}
+		}
+	}
+	if body, err = orderKeywords(body); err != nil || len(extensions) == 0 {
+		return body, err
+	}
+
+	return appendExtensions(body, extensions)
+}
+
+// encodedType returns the value of the "type" keyword of p: nil without a
+// type, the type itself, or for nullable properties a list in canonical
+// order, with the type first and "null" last, so that the encoding is
+// reproducible.
+func (p *property) encodedType() interface{} {
+	switch {
+	case p.Type == "":
+		return nil
+	case p.Nullable && p.Type != "null":
+		return []string{p.Type, "null"}
+	default:
+		return p.Type
+	}
+}
+
+// isNullableSchema reports whether p is nullable but has no type to add
+// "null" to, e.g. a reference, so that null is an alternative to its schema.
+func (p *property) isNullableSchema() bool {
+	return p.Nullable && p.Type == "" && (p.Ref != "" || p.AllOf != nil || p.AnyOf != nil || p.OneOf != nil)
+}
+
+// orderedProperties encodes properties with the names in order first,
+// followed by any others sorted by name.
+type orderedProperties struct {
+	properties map[string]*property
+	order      []string
+}
+
+func (o orderedProperties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	seen := make(map[string]bool, len(o.properties))
+	for _, names := range [][]string{o.order, sortedKeys(o.properties)} {
+		for _, name := range names {
+			p, ok := o.properties[name]
+			if !ok || seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			key, err := json.Marshal(name)
+			if err != nil {
+				return nil, err
+			}
+			value, err := json.Marshal(p)
+			if err != nil {
+				return nil, err
+			}
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func containsNil(values []interface{}) bool {
+	for _, value := range values {
+		if value == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// orderKeywords rearranges the keys of the encoded object body according to
+// keywordOrder, keeping the relative order of all other keys.
+func orderKeywords(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	values := make(map[string]json.RawMessage)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		values[key] = value
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keywordRank(keys[i]) < keywordRank(keys[j])
+	})
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(values[key])
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func keywordRank(keyword string) int {
+	if rank, ok := keywordRanks[keyword]; ok {
+		return rank
+	}
+
+	return len(keywordOrder)
+}
+
+// appendExtensions adds the extensions to the encoded object body in
+// sorted key order, so that the output is reproducible.
+func appendExtensions(body []byte, extensions map[string]interface{}) ([]byte, error) {
+	keys := sortedValueKeys(extensions)
+
+	var buf bytes.Buffer
+	buf.Write(body[:len(body)-1])
+	for i, key := range keys {
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(extensions[key])
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 || len(body) > 2 {
+			buf.WriteByte(',')
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// checkValues returns a GenerationError for the first value, such as a
+// default or an extension, that can't be encoded as JSON, so that the
+// problem is reported with its path when the schema is generated rather
+// than when it is marshalled.
+func (d *Document) checkValues() error {
+	return d.property.checkTree("")
+}
+
+// checkTree is like checkValues for p, found at path, and the properties
+// below it.
+func (p *property) checkTree(path string) error {
+	valid := true
+	p.forEach(func(p *property) {
+		valid = valid && p.checkValues("") == nil
+	})
+	if valid {
+		return nil
+	}
+
+	var err error
+	p.walk(path, func(path string, p *property) {
+		if err == nil {
+			err = p.checkValues(path)
+		}
+	})
+
+	return err
+}
+
+// checkValues returns a GenerationError for the first value of p that can't
+// be encoded as JSON.
+func (p *property) checkValues(path string) error {
+	if p.Default == nil && p.Const == nil && p.Enum == nil && p.Examples == nil && p.Example == nil && p.Extensions == nil {
+		return nil
+	}
+
+	values := []interface{}{p.Default, p.Const, p.Enum, p.Examples, p.Example}
+	for i, keyword := range []string{"default", "const", "enum", "examples", "example"} {
+		if err := checkValue(path, keyword, values[i]); err != nil {
+			return err
+		}
+	}
+	for _, key := range sortedValueKeys(p.Extensions) {
+		if err := checkValue(path, key, p.Extensions[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkValue(path, keyword string, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	if _, err := json.Marshal(value); err != nil {
+		return &GenerationError{Path: path, Reason: fmt.Sprintf("invalid %s value: %v", keyword, err)}
+	}
+
+	return nil
+}
+
+func sortedValueKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// MarshalJSON encodes the Document, with "$schema" as its first key. The
+// definitions are nested according to RefPrefix.
+func (d Document) MarshalJSON() ([]byte, error) {
+	root := d.property
+	if path := d.definitionsPath(); path != nil && root.Definitions != nil {
+		var container interface{} = root.Definitions
+		for i := len(path) - 1; i > 0; i-- {
+			container = map[string]interface{}{path[i]: container}
+		}
+
+		root.Extensions = make(map[string]interface{}, len(d.Extensions)+1)
+		for key, value := range d.Extensions {
+			root.Extensions[key] = value
+		}
+		root.Extensions[path[0]] = container
+		root.Definitions = nil
+	}
+
+	body, err := root.MarshalJSON()
+	if err != nil || d.Schema == "" {
+		return body, err
+	}
+
+	schema, err := json.Marshal(d.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"$schema":`)
+	buf.Write(schema)
+	if d.Vocabulary != nil && d.Schema == Draft202012 {
+		vocabulary, err := json.Marshal(d.Vocabulary)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`,"$vocabulary":`)
+		buf.Write(vocabulary)
+	}
+	if len(body) > 2 {
+		buf.WriteByte(',')
+	}
+	buf.Write(body[1:])
+
+	return buf.Bytes(), nil
+}
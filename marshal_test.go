@@ -0,0 +1,224 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type ExampleJSONExtensions struct {
+	Name string
+}
+
+func TestMarshalExtensions(t *testing.T) {
+	j := &Document{}
+	j.Read(&ExampleJSONExtensions{})
+	j.Extensions = map[string]interface{}{
+		"x-go-type":  "ExampleJSONExtensions",
+		"x-audience": []string{"internal", "public"},
+		"x-beta":     true,
+	}
+	j.Properties["Name"].Extensions = map[string]interface{}{
+		"x-order":    1,
+		"x-column":   "name",
+		"x-nullable": false,
+	}
+
+	expected := `{
+    "$schema": "http://json-schema.org/schema#",
+    "type": "object",
+    "properties": {
+        "Name": {
+            "type": "string",
+            "x-column": "name",
+            "x-nullable": false,
+            "x-order": 1
+        }
+    },
+    "required": [
+        "Name"
+    ],
+    "x-audience": [
+        "internal",
+        "public"
+    ],
+    "x-beta": true,
+    "x-go-type": "ExampleJSONExtensions"
+}`
+
+	for i := 0; i < 10; i++ {
+		if got := j.String(); got != expected {
+			t.Fatalf("run %d: unexpected output:\n%s", i, got)
+		}
+	}
+}
+
+func TestMarshalExtensionsOnEmptyObject(t *testing.T) {
+	p := &property{Extensions: map[string]interface{}{"x-b": 2, "x-a": 1}}
+
+	out, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"x-a":1,"x-b":2}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+}
+
+func TestMarshalExtensionsError(t *testing.T) {
+	p := &property{Type: "string", Extensions: map[string]interface{}{"x-callback": func() {}}}
+
+	if _, err := json.Marshal(p); err == nil {
+		t.Error("expected an error for an unmarshalable extension")
+	}
+}
+
+type ExampleJSONKeywordOrder struct {
+	Mode    string   `json:"mode" jsonschema:"default=fast,enum=fast|slow,comment=tuning"`
+	Created string   `json:"created,omitempty" jsonschema:"format=date-time,examples=2020-01-02T03:04:05Z"`
+	Tags    []string `json:"tags"`
+	Inner   struct {
+		Type string `json:"type"`
+		Enum int    `json:"enum"`
+	} `json:"inner"`
+}
+
+func TestMarshalKeywordOrder(t *testing.T) {
+	j := &Document{}
+	j.SetOpen(ExampleJSONKeywordOrder{})
+	j.Read(&ExampleJSONKeywordOrder{})
+	j.Extensions = map[string]interface{}{"x-go-type": "ExampleJSONKeywordOrder"}
+
+	expected := `{
+    "$schema": "http://json-schema.org/schema#",
+    "type": "object",
+    "properties": {
+        "created": {
+            "type": "string",
+            "format": "date-time",
+            "examples": [
+                "2020-01-02T03:04:05Z"
+            ]
+        },
+        "inner": {
+            "type": "object",
+            "properties": {
+                "enum": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "type",
+                "enum"
+            ]
+        },
+        "mode": {
+            "type": "string",
+            "enum": [
+                "fast",
+                "slow"
+            ],
+            "default": "fast",
+            "$comment": "tuning"
+        },
+        "tags": {
+            "type": "array",
+            "items": {
+                "type": "string"
+            }
+        }
+    },
+    "required": [
+        "mode",
+        "tags",
+        "inner"
+    ],
+    "additionalProperties": true,
+    "x-go-type": "ExampleJSONKeywordOrder"
+}`
+	if got := j.String(); got != expected {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+}
+
+type ExampleJSONNullableTypes struct {
+	Name   *string  `json:"name,omitempty"`
+	Age    *int     `json:"age,omitempty"`
+	Score  *float64 `json:"score,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+func TestMarshalTypeOrder(t *testing.T) {
+	expected := `{"type":"object","properties":{` +
+		`"active":{"type":["boolean","null"]},` +
+		`"age":{"type":["integer","null"]},` +
+		`"name":{"type":["string","null"]},` +
+		`"score":{"type":["number","null"]},` +
+		`"tags":{"type":["array","null"],"items":{"type":"string"}}}}`
+
+	for i := 0; i < 10; i++ {
+		j := &Document{OmitemptyAsNullable: true}
+		j.Read(&ExampleJSONNullableTypes{})
+
+		out, err := json.Marshal(j.property)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != expected {
+			t.Fatalf("run %d: unexpected output %s", i, out)
+		}
+	}
+
+	null := &property{Type: "null", Nullable: true}
+	if typ := null.encodedType(); typ != "null" {
+		t.Errorf("expected a single null type, got %v", typ)
+	}
+}
+
+func TestUnmarshalableValues(t *testing.T) {
+	j := &Document{}
+	j.Override("Name", &property{Type: "string", Default: make(chan int)})
+	err := j.TryRead(&ExampleJSONExtensions{})
+
+	var genErr *GenerationError
+	if !errors.As(err, &genErr) {
+		t.Fatalf("expected a GenerationError, got %v", err)
+	}
+	expected := "jsonschema: Name: invalid default value: json: unsupported type: chan int"
+	if err.Error() != expected {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := j.Marshal(); err == nil || err.Error() != expected {
+		t.Errorf("unexpected marshal error: %v", err)
+	}
+	if got := j.String(); got != expected {
+		t.Errorf("expected String to report the error, got %s", got)
+	}
+	if _, err := j.Bundle(); err == nil || err.Error() != expected {
+		t.Errorf("unexpected bundle error: %v", err)
+	}
+
+	reg := &Registry{}
+	reg.Add("user.json", j)
+	if _, err := reg.Files(); err == nil || err.Error() != "user.json: "+expected {
+		t.Errorf("unexpected files error: %v", err)
+	}
+	if _, err := reg.Bundle(); err == nil {
+		t.Error("expected a registry bundle error")
+	}
+
+	t.Run("extensions", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONExtensions{})
+		j.Properties["Name"].Extensions = map[string]interface{}{"x-callback": func() {}}
+
+		if got := j.String(); got != "jsonschema: Name: invalid x-callback value: json: unsupported type: func()" {
+			t.Errorf("unexpected output: %s", got)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package jsonschema
+
+import (
+	"reflect"
+)
+
+// Merge layers the schema of other on top of the Document, e.g. overrides
+// written by hand over a generated base schema. See Schema.Merge for the
+// rules; the $schema of the Document is kept. Merging nil has no effect.
+func (d *Document) Merge(other *Document) {
+	if other == nil {
+		return
+	}
+	d.property.Merge(&other.property)
+}
+
+// Merge deep-merges other into p. The keywords set in other win over those
+// of p, except that subschemas, such as properties, definitions and items,
+// are merged recursively, the required names are united and extensions are
+// merged by key. Lists of subschemas, like allOf, and values, like enum,
+// are replaced as a whole. Keywords left at their zero value in other, such
+// as a false additionalProperties, are not set. other isn't modified, nor
+// shared with p, and a nil other has no effect.
+func (p *property) Merge(other *property) {
+	if other == nil {
+		return
+	}
+
+	dst := reflect.ValueOf(p).Elem()
+	src := reflect.ValueOf(other).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		from := src.Field(i)
+		if from.IsZero() {
+			continue
+		}
+
+		switch to := dst.Field(i); value := to.Addr().Interface().(type) {
+		case **property:
+			if *value == nil {
+				*value = &property{}
+			}
+			(*value).Merge(from.Interface().(*property))
+		case *map[string]*property:
+			*value = mergeMap(*value, from.Interface().(map[string]*property))
+		case *[]*property:
+			*value = cloneList(from.Interface().([]*property))
+		case *[]string:
+			if dst.Type().Field(i).Name == "Required" {
+				*value = unionStrings(*value, from.Interface().([]string))
+			} else {
+				*value = append([]string(nil), from.Interface().([]string)...)
+			}
+		case **int:
+			n := *from.Interface().(*int)
+			*value = &n
+		case **bool:
+			b := *from.Interface().(*bool)
+			*value = &b
+		case *[]interface{}:
+			*value = append([]interface{}(nil), from.Interface().([]interface{})...)
+		case *map[string]interface{}:
+			merged := make(map[string]interface{}, len(*value))
+			for key, v := range *value {
+				merged[key] = v
+			}
+			for key, v := range from.Interface().(map[string]interface{}) {
+				merged[key] = v
+			}
+			*value = merged
+		default:
+			to.Set(from)
+		}
+	}
+}
+
+// mergeMap merges the subschemas of other into those of m by name.
+func mergeMap(m, other map[string]*property) map[string]*property {
+	if m == nil {
+		m = make(map[string]*property, len(other))
+	}
+	for name, p := range other {
+		if m[name] == nil {
+			m[name] = &property{}
+		}
+		m[name].Merge(p)
+	}
+
+	return m
+}
+
+// unionStrings appends the items of other missing from list.
+func unionStrings(list, other []string) []string {
+	union := append([]string(nil), list...)
+	for _, item := range other {
+		if !containsString(union, item) {
+			union = append(union, item)
+		}
+	}
+
+	return union
+}
@@ -0,0 +1,85 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type MergeAddress struct {
+	Street string `json:"street"`
+	Zip    string `json:"zip,omitempty"`
+}
+
+type ExampleJSONMerge struct {
+	Name    string       `json:"name"`
+	Tags    []string     `json:"tags,omitempty"`
+	Address MergeAddress `json:"address"`
+}
+
+func TestMerge(t *testing.T) {
+	two, maxItems := 2, 2
+	j := &Document{}
+	j.Read(&ExampleJSONMerge{})
+
+	overrides := &Document{}
+	overrides.Properties = map[string]*property{
+		"name": {Description: "The full name", Pattern: "^[A-Z]"},
+		"tags": {Items: &property{Enum: []interface{}{"a", "b"}}, MaxItems: &two},
+		"address": {
+			Properties: map[string]*property{"zip": {Type: "integer"}},
+			Required:   []string{"zip", "street"},
+		},
+		"extra": {Type: "boolean"},
+	}
+	overrides.Required = []string{"tags"}
+	overrides.Extensions = map[string]interface{}{"x-go-type": "ExampleJSONMerge"}
+	j.Merge(overrides)
+
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"name": {Type: "string", Description: "The full name", Pattern: "^[A-Z]"},
+			"tags": {Type: "array", Items: &property{Type: "string", Enum: []interface{}{"a", "b"}}, MaxItems: &maxItems},
+			"address": {
+				Type: "object",
+				Properties: map[string]*property{
+					"street": {Type: "string"},
+					"zip":    {Type: "integer"},
+				},
+				Required: []string{"street", "zip"},
+			},
+			"extra": {Type: "boolean"},
+		},
+		Required:   []string{"name", "address", "tags"},
+		Extensions: map[string]interface{}{"x-go-type": "ExampleJSONMerge"},
+	}
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("other is not shared", func(t *testing.T) {
+		*overrides.Properties["tags"].MaxItems = 5
+		overrides.Properties["address"].Properties["zip"].Type = "string"
+		overrides.Properties["tags"].Items.Enum[0] = "c"
+		if diff := cmp.Diff(expected, j.property); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("lists are replaced", func(t *testing.T) {
+		p := &property{Enum: []interface{}{1, 2}, AllOf: []*property{{Type: "string"}}}
+		p.Merge(&property{Enum: []interface{}{3}, AllOf: []*property{{Type: "number"}, {Type: "integer"}}})
+
+		expected := &property{Enum: []interface{}{3}, AllOf: []*property{{Type: "number"}, {Type: "integer"}}}
+		if diff := cmp.Diff(expected, p); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("nil", func(t *testing.T) {
+		j.Merge(nil)
+		j.property.Merge(nil)
+		if diff := cmp.Diff(expected, j.property); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
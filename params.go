@@ -0,0 +1,39 @@
+package jsonschema
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ReadParams reads the struct describing the parameters of a method, e.g.
+// for an RPC framework, into the Document. The properties are emitted in
+// the order of the fields, which is the order of the parameters, and each
+// one is marked with its zero-based index in the "x-position" extension.
+// It returns the first problem found while reading, like TryRead.
+func (d *Document) ReadParams(params interface{}) error {
+	t := reflect.TypeOf(params)
+	if t == nil || derefType(t).Kind() != reflect.Struct {
+		return &GenerationError{Type: fmt.Sprint(t), Reason: "parameters must be a struct"}
+	}
+	if err := d.readType(t); err != nil {
+		return err
+	}
+
+	d.Order = nil
+	for _, name := range fieldNames(derefType(t)) {
+		if _, ok := d.Properties[name]; ok && !containsString(d.Order, name) {
+			d.Order = append(d.Order, name)
+		}
+	}
+	for i, name := range d.Order {
+		param := d.Properties[name]
+		extensions := make(map[string]interface{}, len(param.Extensions)+1)
+		for key, value := range param.Extensions {
+			extensions[key] = value
+		}
+		extensions["x-position"] = i
+		param.Extensions = extensions
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type ParamsPaging struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type ExampleJSONParams struct {
+	UserID string `json:"userId"`
+	ParamsPaging
+	Filter  string `json:"filter,omitempty"`
+	Verbose bool   `json:"-"`
+}
+
+func TestReadParams(t *testing.T) {
+	j := &Document{}
+	if err := j.ReadParams(&ExampleJSONParams{}); err != nil {
+		t.Fatal(err)
+	}
+
+	positions := make(map[string]interface{})
+	for name, p := range j.Properties {
+		positions[name] = p.Extensions["x-position"]
+	}
+	expected := map[string]interface{}{"userId": 0, "limit": 1, "offset": 2, "filter": 3}
+	if diff := cmp.Diff(expected, positions); diff != "" {
+		t.Error(diff)
+	}
+
+	doc, err := json.Marshal(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedOrder := `"properties":{"userId":{"type":"string","x-position":0},"limit":{"type":"integer","x-position":1},"offset":{"type":"integer","x-position":2},"filter":{"type":"string","x-position":3}}`
+	if !strings.Contains(string(doc), expectedOrder) {
+		t.Errorf("expected properties in parameter order, got %s", doc)
+	}
+
+	t.Run("not a struct", func(t *testing.T) {
+		j := &Document{}
+		if err := j.ReadParams(42); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
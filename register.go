@@ -0,0 +1,299 @@
+package jsonschema
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// RegisterEnum declares the values of an enum type defined as a Go const
+// group, such as `type Color int` with `Red`, `Green` and `Blue`, since
+// reflection can't see the constants. sample is any value of the type and
+// values maps each constant to its name.
+//
+// Wherever the type occurs it is emitted with an enum: of the names when the
+// type marshals itself as text or JSON, or of the constant values otherwise.
+// Pointers to the type are nullable, accepting null in the enum as well.
+func (d *Document) RegisterEnum(sample interface{}, values map[interface{}]string) {
+	t := reflect.TypeOf(sample)
+
+	constants := make([]reflect.Value, 0, len(values))
+	for k := range values {
+		v := reflect.ValueOf(k)
+		if !v.IsValid() || !v.Type().ConvertibleTo(t) {
+			continue
+		}
+		constants = append(constants, v.Convert(t))
+	}
+	sort.Slice(constants, func(i, j int) bool {
+		return lessValue(constants[i], constants[j])
+	})
+
+	enum := &property{}
+	if marshalsItself(t) {
+		enum.Type = "string"
+		for _, c := range constants {
+			enum.Enum = append(enum.Enum, values[c.Interface()])
+		}
+	} else {
+		enum.Type, _, _ = d.getTypeFromMapping(t)
+		for _, c := range constants {
+			enum.Enum = append(enum.Enum, underlyingValue(c))
+		}
+	}
+
+	if d.enums == nil {
+		d.enums = make(map[reflect.Type]*property)
+	}
+	d.enums[t] = enum
+}
+
+// RegisterStringEnum declares the values of a named string type, such as
+// `type Status string`, which is then emitted as a string with an enum of
+// values, in the given order. Pointers to the type are nullable, like those
+// of RegisterEnum.
+func (d *Document) RegisterStringEnum(sample interface{}, values ...string) {
+	enum := &property{Type: "string"}
+	for _, value := range values {
+		enum.Enum = append(enum.Enum, value)
+	}
+
+	if d.enums == nil {
+		d.enums = make(map[reflect.Type]*property)
+	}
+	d.enums[reflect.TypeOf(sample)] = enum
+}
+
+// RegisterFormat emits the type of sample, wherever it occurs, as jsType
+// with the given format, e.g. a date-only type as "string" with "date". It
+// takes precedence over the built-in mappings.
+func (d *Document) RegisterFormat(sample interface{}, jsType, format string) {
+	if d.formats == nil {
+		d.formats = make(map[reflect.Type][]string)
+	}
+	d.formats[reflect.TypeOf(sample)] = []string{jsType, format}
+}
+
+// RegisterType makes fn generate the schema of the type of sample wherever
+// it occurs, instead of reflecting the type. It is meant for types the
+// other registrations can't describe, and takes precedence over them.
+func (d *Document) RegisterType(sample interface{}, fn func() *Schema) {
+	if d.generators == nil {
+		d.generators = make(map[reflect.Type]func() *property)
+	}
+	d.generators[reflect.TypeOf(sample)] = fn
+}
+
+// RegisterImplementations describes the interface type of iface, given as
+// a pointer such as (*Shape)(nil), wherever it occurs, including at the
+// root with ReadType, as a oneOf of the types of impls.
+func (d *Document) RegisterImplementations(iface interface{}, impls ...interface{}) {
+	t := reflect.TypeOf(iface)
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
+		t = t.Elem()
+	}
+
+	if d.impls == nil {
+		d.impls = make(map[reflect.Type][]reflect.Type)
+	}
+	d.impls[t] = nil
+	for _, impl := range impls {
+		d.impls[t] = append(d.impls[t], reflect.TypeOf(impl))
+	}
+}
+
+// SetOpen marks the struct type of sample as extensible, so that its object
+// schema allows additional properties.
+func (d *Document) SetOpen(sample interface{}) {
+	if d.open == nil {
+		d.open = make(map[reflect.Type]bool)
+	}
+	d.open[derefType(reflect.TypeOf(sample))] = true
+}
+
+// PropertyOrder emits the properties of the struct type named typeName,
+// e.g. "Address" or "model.Address", in the given order instead of sorted
+// by name. The properties that aren't listed follow in the order of their
+// fields.
+func (d *Document) PropertyOrder(typeName string, order []string) {
+	if d.orders == nil {
+		d.orders = make(map[string][]string)
+	}
+	d.orders[typeName] = order
+}
+
+// propertyOrderOf returns the order registered for the struct type t of the
+// given properties, or nil.
+func (d *Document) propertyOrderOf(t reflect.Type, properties map[string]*property) []string {
+	order, ok := d.orders[t.Name()]
+	if !ok || t.Name() == "" {
+		order, ok = d.orders[t.String()]
+	}
+	if !ok || len(properties) == 0 {
+		return nil
+	}
+
+	ordered := make([]string, 0, len(properties))
+	seen := make(map[string]bool, len(properties))
+	for _, names := range [][]string{order, fieldNames(t), sortedKeys(properties)} {
+		for _, name := range names {
+			if _, ok := properties[name]; ok && !seen[name] {
+				seen[name] = true
+				ordered = append(ordered, name)
+			}
+		}
+	}
+
+	return ordered
+}
+
+// fieldNames returns the property names of the fields of the struct type t
+// in their declaration order, including those promoted from embedded
+// structs.
+func fieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name, _ := parseTag(tag)
+		if name == "" {
+			name = field.Name
+		}
+
+		switch {
+		case tag == "-":
+		case isEmbeddedStruct(field):
+			names = append(names, fieldNames(derefType(field.Type))...)
+		default:
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// hasRegistrations reports whether any type was registered with the
+// Document.
+func (d *Document) hasRegistrations() bool {
+	return len(d.enums) > 0 || len(d.open) > 0 || len(d.formats) > 0 || len(d.generators) > 0 || len(d.impls) > 0 || len(d.orders) > 0 ||
+		len(d.definedAs) > 0
+}
+
+// isRegistered reports whether the schema of t is given by a registration.
+func (d *Document) isRegistered(t reflect.Type) bool {
+	_, generated := d.generators[t]
+	_, enum := d.enums[t]
+	_, implemented := d.impls[t]
+	return generated || enum || implemented
+}
+
+// readRegistered fills p from the registrations for t and reports whether
+// one was found.
+func (d *Document) readRegistered(p *property, t reflect.Type) bool {
+	if fn, ok := d.generators[t]; ok {
+		// The schema is copied, as fn may return the same one every time
+		// and the options applied after the read modify it in place.
+		if generated := fn(); generated != nil {
+			*p = *generated.clone()
+		}
+		return true
+	}
+	if enum, ok := d.enums[t]; ok {
+		p.Type = enum.Type
+		p.Enum = append([]interface{}(nil), enum.Enum...)
+		return true
+	}
+	if impls, ok := d.impls[t]; ok {
+		p.OneOf = make([]*property, len(impls))
+		for i, impl := range impls {
+			parent := d.path
+			d.path = joinPath(parent, fmt.Sprintf("oneOf[%d]", i))
+			p.OneOf[i] = &property{}
+			p.OneOf[i].read(d, impl, "")
+			d.path = parent
+		}
+		return true
+	}
+
+	return false
+}
+
+// stringifyEnums replaces the enum values that aren't strings with their
+// JSON encoding when StringEnums is set. Values that can't be encoded are
+// kept, so that they are reported by checkValues.
+func (d *Document) stringifyEnums(root *property) {
+	if !d.StringEnums {
+		return
+	}
+
+	root.forEach(func(p *property) {
+		if p.Enum == nil {
+			return
+		}
+
+		enum := make([]interface{}, len(p.Enum))
+		for i, value := range p.Enum {
+			enum[i] = value
+			if _, ok := value.(string); ok || value == nil {
+				continue
+			}
+			if encoded, err := json.Marshal(value); err == nil {
+				enum[i] = string(encoded)
+			}
+		}
+		p.Enum = enum
+	})
+}
+
+func marshalsItself(t reflect.Type) bool {
+	for _, m := range []reflect.Type{textMarshalerType, jsonMarshalerType} {
+		if t.Implements(m) || reflect.PtrTo(t).Implements(m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// underlyingValue returns v as a value of its basic kind, so that named
+// types are encoded like their underlying type.
+func underlyingValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	default:
+		return v.Interface()
+	}
+}
+
+func lessValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	default:
+		return false
+	}
+}
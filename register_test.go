@@ -0,0 +1,537 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+type Weekday uint8
+
+const (
+	Sunday Weekday = iota
+	Monday
+)
+
+func (w Weekday) MarshalText() ([]byte, error) {
+	return []byte([]string{"sunday", "monday"}[w]), nil
+}
+
+type ExampleJSONEnums struct {
+	Color    Color
+	Palette  []Color   `json:",omitempty"`
+	Favorite *Color    `json:",omitempty"`
+	Day      Weekday   `json:",omitempty"`
+	Days     []Weekday `json:",omitempty"`
+}
+
+func TestRegisterEnum(t *testing.T) {
+	j := &Document{}
+	j.RegisterEnum(Color(0), map[interface{}]string{Blue: "blue", Red: "red", Green: "green"})
+	j.RegisterEnum(Weekday(0), map[interface{}]string{Sunday: "sunday", Monday: "monday"})
+	j.Read(&ExampleJSONEnums{})
+
+	colors := &property{Type: "integer", Enum: []interface{}{int64(0), int64(1), int64(2)}}
+	days := &property{Type: "string", Enum: []interface{}{"sunday", "monday"}}
+	expected := map[string]*property{
+		"Color":    colors,
+		"Palette":  {Type: "array", Items: colors},
+		"Favorite": {Type: "integer", Nullable: true, Enum: colors.Enum},
+		"Day":      days,
+		"Days":     {Type: "array", Items: days},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("deep", func(t *testing.T) {
+		deep := &Document{}
+		deep.RegisterEnum(Color(0), map[interface{}]string{Red: "red", Green: "green"})
+		deep.ReadDeep(map[string]interface{}{"color": Green})
+
+		if diff := cmp.Diff(&property{Type: "integer", Enum: []interface{}{int64(0), int64(1)}}, deep.Properties["color"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("unregistered", func(t *testing.T) {
+		plain := &Document{}
+		plain.Read(&ExampleJSONEnums{})
+
+		if plain.Properties["Color"].Enum != nil {
+			t.Errorf("unexpected enum %v", plain.Properties["Color"].Enum)
+		}
+	})
+}
+
+func TestStringEnums(t *testing.T) {
+	read := func(stringEnums bool) string {
+		j := &Document{StringEnums: stringEnums}
+		j.RegisterEnum(Color(0), map[interface{}]string{Blue: "blue", Red: "red", Green: "green"})
+		j.RegisterEnum(Weekday(0), map[interface{}]string{Sunday: "sunday", Monday: "monday"})
+		j.Read(&ExampleJSONEnums{})
+
+		out, err := json.Marshal(map[string]*property{"Color": j.Properties["Color"], "Day": j.Properties["Day"]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+
+	if out := read(false); out != `{"Color":{"type":"integer","enum":[0,1,2]},"Day":{"type":"string","enum":["sunday","monday"]}}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+	if out := read(true); out != `{"Color":{"type":"integer","enum":["0","1","2"]},"Day":{"type":"string","enum":["sunday","monday"]}}` {
+		t.Errorf("unexpected JSON with StringEnums: %s", out)
+	}
+
+	t.Run("tags", func(t *testing.T) {
+		j := &Document{StringEnums: true}
+		j.ReadDeep(struct {
+			Ratio float64 `jsonschema:"enum=0.5|1.5"`
+			Flag  bool    `jsonschema:"enum=true"`
+		}{})
+
+		if diff := cmp.Diff([]interface{}{"0.5", "1.5"}, j.Properties["Ratio"].Enum); diff != "" {
+			t.Error(diff)
+		}
+		if diff := cmp.Diff([]interface{}{"true"}, j.Properties["Flag"].Enum); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+type Status string
+
+type ExampleJSONStringEnum struct {
+	Status   Status
+	History  []Status          `json:",omitempty"`
+	Previous *Status           `json:",omitempty"`
+	ByRegion map[string]Status `json:",omitempty"`
+	Label    string
+}
+
+func TestRegisterStringEnum(t *testing.T) {
+	j := &Document{}
+	j.RegisterStringEnum(Status(""), "active", "inactive")
+	j.Read(&ExampleJSONStringEnum{})
+
+	status := &property{Type: "string", Enum: []interface{}{"active", "inactive"}}
+	expected := map[string]*property{
+		"Status":   status,
+		"History":  {Type: "array", Items: status},
+		"Previous": {Type: "string", Nullable: true, Enum: status.Enum},
+		"ByRegion": {Type: "object", Properties: map[string]*property{".*": status}},
+		"Label":    {Type: "string"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties["Status"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":"string","enum":["active","inactive"]}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+}
+
+type ExampleJSONStringEnumPointer struct {
+	Previous *Status
+}
+
+func TestRegisterStringEnumNullable(t *testing.T) {
+	j := &Document{}
+	j.RegisterStringEnum(Status(""), "active", "inactive")
+	j.Read(&ExampleJSONStringEnumPointer{})
+
+	previous := j.Properties["Previous"]
+	if diff := cmp.Diff(&property{Type: "string", Nullable: true, Enum: []interface{}{"active", "inactive"}}, previous); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":["string","null"],"enum":["active","inactive",null]}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+	if len(previous.Enum) != 2 {
+		t.Errorf("marshalling changed the enum: %v", previous.Enum)
+	}
+
+	t.Run("omitempty", func(t *testing.T) {
+		j := &Document{OmitemptyAsNullable: true}
+		j.RegisterStringEnum(Status(""), "active", "inactive")
+		j.Read(&ExampleJSONStringEnum{})
+
+		if status := j.Properties["Status"]; status.Nullable {
+			t.Errorf("unexpected nullable value: %+v", status)
+		}
+		if previous := j.Properties["Previous"]; !previous.Nullable {
+			t.Errorf("expected a nullable pointer: %+v", previous)
+		}
+	})
+	t.Run("deep", func(t *testing.T) {
+		active := Status("active")
+		for _, value := range []*ExampleJSONStringEnumPointer{{}, {Previous: &active}} {
+			deep := &Document{InferNilPointerTypes: true}
+			deep.RegisterStringEnum(Status(""), "active", "inactive")
+			deep.ReadDeep(value)
+
+			out, err := json.Marshal(deep.Properties["Previous"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != `{"type":["string","null"],"enum":["active","inactive",null]}` {
+				t.Errorf("unexpected JSON: %s", out)
+			}
+		}
+	})
+}
+
+type ExampleJSONEnumMap struct {
+	ByRegion map[string]Status
+}
+
+func TestRegisterStringEnumMapValues(t *testing.T) {
+	status := &property{Type: "string", Enum: []interface{}{"active", "inactive"}}
+	expected := &property{Type: "object", Properties: map[string]*property{".*": status}}
+
+	j := &Document{}
+	j.RegisterStringEnum(Status(""), "active", "inactive")
+	j.Read(map[string]Status{})
+	if diff := cmp.Diff(*expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("deep", func(t *testing.T) {
+		deep := &Document{}
+		deep.RegisterStringEnum(Status(""), "active", "inactive")
+		deep.ReadDeep(&ExampleJSONEnumMap{ByRegion: map[string]Status{"eu": "active"}})
+
+		if diff := cmp.Diff(status, deep.Properties["ByRegion"].Properties["eu"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("deep empty", func(t *testing.T) {
+		deep := &Document{}
+		deep.RegisterStringEnum(Status(""), "active", "inactive")
+		deep.ReadDeep(&ExampleJSONEnumMap{ByRegion: map[string]Status{}})
+
+		if diff := cmp.Diff(expected, deep.Properties["ByRegion"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("definitions", func(t *testing.T) {
+		defs := &Document{UseDefinitions: true}
+		defs.RegisterStringEnum(Status(""), "active", "inactive")
+		defs.Read(&struct{ Config ExampleJSONEnumMap }{})
+
+		if diff := cmp.Diff(expected, defs.Definitions["ExampleJSONEnumMap"].Properties["ByRegion"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+type OrderedBase struct {
+	ID string `json:"id"`
+}
+
+type OrderedForm struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	OrderedBase
+	Age     int    `json:"age"`
+	Comment string `json:"comment,omitempty"`
+}
+
+func TestPropertyOrder(t *testing.T) {
+	j := &Document{}
+	j.PropertyOrder("OrderedForm", []string{"email", "age", "missing"})
+	j.Read(&struct {
+		Form  OrderedForm
+		Other OrderedBase
+	}{})
+
+	if diff := cmp.Diff([]string{"email", "age", "name", "id", "comment"}, j.Properties["Form"].Order); diff != "" {
+		t.Error(diff)
+	}
+	if j.Properties["Other"].Order != nil {
+		t.Errorf("unexpected order %v", j.Properties["Other"].Order)
+	}
+
+	out, err := json.Marshal(j.Properties["Form"].Properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"age":{"type":"integer"},"comment":{"type":"string"},"email":{"type":"string"},"id":{"type":"string"},"name":{"type":"string"}}` {
+		t.Errorf("unexpected JSON of the map: %s", out)
+	}
+	out, err = json.Marshal(j.Properties["Form"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"type":"object","properties":{"email":{"type":"string"},"age":{"type":"integer"},"name":{"type":"string"},` +
+		`"id":{"type":"string"},"comment":{"type":"string"}},"required":["name","email","id","age"]}`
+	if string(out) != expected {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	t.Run("qualified name", func(t *testing.T) {
+		deep := &Document{}
+		deep.PropertyOrder("jsonschema.OrderedForm", []string{"comment"})
+		deep.ReadDeep(&OrderedForm{})
+
+		if diff := cmp.Diff([]string{"comment", "name", "email", "id", "age"}, deep.Order); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+type OpenConfig struct {
+	Name string
+}
+
+type ExampleJSONOpen struct {
+	Config  OpenConfig
+	Configs []*OpenConfig `json:",omitempty"`
+	Closed  struct {
+		Name string
+	}
+}
+
+func TestSetOpen(t *testing.T) {
+	j := &Document{}
+	j.SetOpen(OpenConfig{})
+	j.Read(&ExampleJSONOpen{})
+
+	if !j.Properties["Config"].AdditionalProperties {
+		t.Error("expected Config to allow additional properties")
+	}
+	if !j.Properties["Configs"].Items.AdditionalProperties {
+		t.Error("expected Configs items to allow additional properties")
+	}
+	if j.Properties["Closed"].AdditionalProperties || j.AdditionalProperties {
+		t.Error("expected other structs to stay closed")
+	}
+
+	t.Run("pointer sample and root", func(t *testing.T) {
+		j := &Document{}
+		j.SetOpen(&OpenConfig{})
+		j.ReadDeep(&OpenConfig{})
+
+		out, err := j.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(out, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded["additionalProperties"] != true {
+			t.Errorf("expected additionalProperties:true, got %s", out)
+		}
+	})
+}
+
+type CivilDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+type ExampleJSONDates struct {
+	Birthday  CivilDate
+	Holidays  []CivilDate          `json:",omitempty"`
+	Deadlines map[string]CivilDate `json:",omitempty"`
+	Optional  *CivilDate           `json:",omitempty"`
+	Created   time.Time
+}
+
+func TestRegisterFormat(t *testing.T) {
+	j := &Document{}
+	j.RegisterFormat(CivilDate{}, "string", "date")
+	j.Read(&ExampleJSONDates{})
+
+	date := &property{Type: "string", Format: "date"}
+	expected := map[string]*property{
+		"Birthday":  date,
+		"Holidays":  {Type: "array", Items: date},
+		"Deadlines": {Type: "object", Properties: map[string]*property{".*": date}},
+		"Optional":  date,
+		"Created":   {Type: "string", Format: "date-time"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("overrides built-in mappings", func(t *testing.T) {
+		j := &Document{}
+		j.RegisterFormat(time.Time{}, "integer", "unix-time")
+		j.Read(&ExampleJSONDates{})
+
+		if diff := cmp.Diff(&property{Type: "integer", Format: "unix-time"}, j.Properties["Created"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("deep", func(t *testing.T) {
+		j := &Document{}
+		j.RegisterFormat(CivilDate{}, "string", "date")
+		j.ReadDeep(map[string]interface{}{"day": CivilDate{Year: 2024}})
+
+		if diff := cmp.Diff(date, j.Properties["day"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+func TestRegisterType(t *testing.T) {
+	calls := 0
+	unixTime := func() *property {
+		calls++
+		return &property{Type: "integer", Comment: "seconds since the epoch"}
+	}
+
+	j := &Document{}
+	j.RegisterType(time.Time{}, unixTime)
+	j.RegisterFormat(time.Time{}, "string", "date")
+	j.Read(&ExampleJSONDates{})
+
+	expected := &property{Type: "integer", Comment: "seconds since the epoch"}
+	if diff := cmp.Diff(expected, j.Properties["Created"]); diff != "" {
+		t.Error(diff)
+	}
+	if calls != 1 {
+		t.Errorf("expected one call, got %d", calls)
+	}
+
+	t.Run("deep", func(t *testing.T) {
+		j := &Document{}
+		j.RegisterType(time.Time{}, unixTime)
+		j.ReadDeep(map[string]interface{}{"at": time.Now()})
+
+		if diff := cmp.Diff(expected, j.Properties["at"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("nil schema", func(t *testing.T) {
+		j := &Document{}
+		j.RegisterType(time.Time{}, func() *property { return nil })
+		j.Read(&ExampleJSONDates{})
+
+		if diff := cmp.Diff(&property{}, j.Properties["Created"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("shared schema", func(t *testing.T) {
+		type Location struct{ Zip int }
+		shared := &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"Zip": {Type: "integer", Format: "zip"}},
+		}
+
+		j := &Document{DisableFormats: true}
+		j.RegisterType(Location{}, func() *Schema { return shared })
+		j.Override("Home.Zip", &Schema{Type: "string"})
+		j.Read(&struct {
+			Home Location
+			Work Location
+		}{})
+
+		if zip := j.Properties["Home"].Properties["Zip"]; zip.Type != "string" {
+			t.Errorf("override not applied: %+v", zip)
+		}
+		if diff := cmp.Diff(&Schema{Type: "integer"}, j.Properties["Work"].Properties["Zip"]); diff != "" {
+			t.Error(diff)
+		}
+		if diff := cmp.Diff(&Schema{Type: "integer", Format: "zip"}, shared.Properties["Zip"]); diff != "" {
+			t.Errorf("the registered schema was modified: %s", diff)
+		}
+	})
+}
+
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Radius float64 `json:"radius"`
+}
+
+func (c Circle) Area() float64 { return 3.14 * c.Radius * c.Radius }
+
+type Square struct {
+	Side float64 `json:"side"`
+}
+
+func (s *Square) Area() float64 { return s.Side * s.Side }
+
+func TestRegisterImplementations(t *testing.T) {
+	circle := &property{Type: "object", Properties: map[string]*property{"radius": {Type: "number"}}, Required: []string{"radius"}}
+	square := &property{Type: "object", Properties: map[string]*property{"side": {Type: "number"}}, Required: []string{"side"}}
+
+	j := &Document{}
+	j.RegisterImplementations((*Shape)(nil), Circle{}, &Square{})
+	j.ReadType(reflect.TypeOf((*Shape)(nil)).Elem())
+
+	if diff := cmp.Diff(property{OneOf: []*property{circle, square}}, j.property); diff != "" {
+		t.Error(diff)
+	}
+	out, err := j.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if oneOf, _ := decoded["oneOf"].([]interface{}); len(oneOf) != 2 || decoded["$schema"] == nil {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	t.Run("nested", func(t *testing.T) {
+		j := &Document{UseDefinitions: true}
+		j.RegisterImplementations((*Shape)(nil), Circle{}, &Square{})
+		j.Read(&struct {
+			Shapes []Shape
+		}{})
+
+		expected := &property{OneOf: []*property{{Ref: "#/definitions/Circle"}, {Ref: "#/definitions/Square"}}}
+		if diff := cmp.Diff(expected, j.Properties["Shapes"].Items); diff != "" {
+			t.Error(diff)
+		}
+		if diff := cmp.Diff(map[string]*property{"Circle": circle, "Square": square}, j.Definitions); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("slices", func(t *testing.T) {
+		expected := &property{Type: "array", Items: &property{OneOf: []*property{circle, square}}}
+
+		j := &Document{}
+		j.RegisterImplementations((*Shape)(nil), Circle{}, &Square{})
+		j.Read(&struct{ Shapes []Shape }{})
+		if diff := cmp.Diff(expected, j.Properties["Shapes"]); diff != "" {
+			t.Error(diff)
+		}
+
+		deep := &Document{}
+		deep.RegisterImplementations((*Shape)(nil), Circle{}, &Square{})
+		deep.ReadDeep(&struct{ Shapes []Shape }{Shapes: []Shape{nil, Circle{Radius: 1}, &Square{}}})
+		if diff := cmp.Diff(expected, deep.Properties["Shapes"]); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package jsonschema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Registry holds named Documents that refer to each other. A definition in
+// one Document with the same schema as the root of another Document, as
+// when both are read from the same type with the same options, is turned
+// into a reference to that Document, so that types described by their own
+// schema are not repeated in the schemas using them. Definitions are only
+// made with UseDefinitions, so that option must be set on the Documents
+// holding the references.
+type Registry struct {
+	names     []string
+	documents map[string]*Document
+}
+
+// Add registers the Document doc, which has been read already, under name.
+// The name is used as the URI of the Document in references to it, e.g.
+// "user.json", and replaces any Document added before under the same name.
+func (r *Registry) Add(name string, doc *Document) {
+	if r.documents == nil {
+		r.documents = make(map[string]*Document)
+	}
+	if _, ok := r.documents[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.documents[name] = doc
+}
+
+// Files returns the encoding of every Document by its name, with the
+// references to other Documents pointing to their names, e.g.
+// {"$ref": "user.json"}, and without the definitions they replace. It
+// returns the first error that prevented encoding a Document.
+func (r *Registry) Files() (map[string][]byte, error) {
+	files := make(map[string][]byte, len(r.names))
+	for _, name := range r.names {
+		doc := r.documents[name]
+		file := Document{Schema: doc.Schema, Vocabulary: doc.Vocabulary, RefPrefix: doc.RefPrefix, property: *doc.property.clone()}
+		external := r.externalRefs(name)
+		file.forEach(func(p *property) {
+			if target, ok := external[p.Ref]; ok {
+				p.Ref = target
+			}
+		})
+		file.pruneDefinitions()
+
+		out, err := file.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		files[name] = out
+	}
+
+	return files, nil
+}
+
+// Bundle returns every Document as a definition of a single
+// self-contained schema named after the Document, and with the dialect of
+// the first one. The definitions of the Documents are merged, those
+// sharing a name but not their schema are told apart by the name of their
+// Document, e.g. "user.json.Address". Like Document.Bundle, it emits the
+// definitions under "$defs" for draft 2019-09 and later.
+func (r *Registry) Bundle() ([]byte, error) {
+	bundle := &Document{}
+	if len(r.names) > 0 {
+		bundle.Schema = r.documents[r.names[0]].Schema
+	}
+	bundle.Definitions = make(map[string]*property)
+
+	// merged holds the definitions as read, before their references are
+	// rewritten, to find the ones shared by several Documents.
+	merged := make(map[string]*property)
+	for _, name := range r.names {
+		merged[name] = nil
+	}
+
+	for _, name := range r.names {
+		doc := r.documents[name]
+		root := doc.property.clone()
+		definitions := root.Definitions
+		root.Definitions = nil
+
+		refs := make(map[string]string)
+		for target, document := range r.externalRefs(name) {
+			refs[target] = definitionsPrefix + document
+		}
+		var added []string
+		for _, def := range sortedKeys(definitions) {
+			ref := doc.refPrefix() + def
+			if _, ok := refs[ref]; ok {
+				continue
+			}
+
+			qualified := def
+			if existing, ok := merged[def]; ok {
+				if existing != nil && reflect.DeepEqual(existing, definitions[def]) {
+					refs[ref] = definitionsPrefix + def
+					continue
+				}
+				qualified = name + "." + def
+			}
+			merged[qualified] = definitions[def].clone()
+			bundle.Definitions[qualified] = definitions[def]
+			refs[ref] = definitionsPrefix + qualified
+			added = append(added, qualified)
+		}
+
+		rewrite := func(p *property) {
+			if target, ok := refs[p.Ref]; ok {
+				p.Ref = target
+			}
+		}
+		root.forEach(rewrite)
+		for _, def := range added {
+			bundle.Definitions[def].forEach(rewrite)
+		}
+		bundle.Definitions[name] = root
+	}
+
+	return bundle.Bundle()
+}
+
+// externalRefs maps the references of the Document name to its
+// definitions that have the schema of the root of another Document to the
+// name of that Document.
+func (r *Registry) externalRefs(name string) map[string]string {
+	doc := r.documents[name]
+	refs := make(map[string]string)
+	for _, other := range r.names {
+		if other == name {
+			continue
+		}
+		root := r.documents[other].property
+		root.Definitions = nil
+		for _, def := range sortedKeys(doc.Definitions) {
+			if reflect.DeepEqual(doc.Definitions[def], &root) {
+				refs[doc.refPrefix()+def] = other
+			}
+		}
+	}
+
+	return refs
+}
+
+// pruneDefinitions removes the definitions that are no longer referenced,
+// directly or through other definitions, from the root of d.
+func (d *Document) pruneDefinitions() {
+	if d.Definitions == nil {
+		return
+	}
+
+	definitions := d.Definitions
+	d.Definitions = nil
+	used := make(map[string]bool)
+	var visit func(p *property)
+	visit = func(p *property) {
+		p.forEach(func(p *property) {
+			name := strings.TrimPrefix(p.Ref, d.refPrefix())
+			if p.Ref == "" || name == p.Ref || used[name] || definitions[name] == nil {
+				return
+			}
+			used[name] = true
+			visit(definitions[name])
+		})
+	}
+	visit(&d.property)
+
+	for name := range definitions {
+		if !used[name] {
+			delete(definitions, name)
+		}
+	}
+	if len(definitions) > 0 {
+		d.Definitions = definitions
+	}
+}
@@ -0,0 +1,205 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type RegistryUser struct {
+	Name    string
+	Address DefinitionAddress
+}
+
+type RegistryOrder struct {
+	ID       int
+	Buyer    RegistryUser
+	Sellers  []RegistryUser `json:",omitempty"`
+	Shipping DefinitionAddress
+}
+
+func newRegistry(schema string) *Registry {
+	user := NewDocument(schema)
+	user.UseDefinitions = true
+	user.Read(&RegistryUser{})
+
+	order := NewDocument(schema)
+	order.UseDefinitions = true
+	order.Read(&RegistryOrder{})
+
+	reg := &Registry{}
+	reg.Add("user.json", user)
+	reg.Add("order.json", order)
+	return reg
+}
+
+func TestRegistryFiles(t *testing.T) {
+	files, err := newRegistry("http://json-schema.org/draft-07/schema#").Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order map[string]interface{}
+	if err := json.Unmarshal(files["order.json"], &order); err != nil {
+		t.Fatal(err)
+	}
+	refs := collectRefs(order["properties"])
+	if diff := cmp.Diff([]string{"#/definitions/DefinitionAddress", "user.json", "user.json"}, sortedStrings(refs)); diff != "" {
+		t.Error(diff)
+	}
+	definitions, _ := order["definitions"].(map[string]interface{})
+	if _, ok := definitions["RegistryUser"]; ok || len(definitions) != 1 {
+		t.Errorf("unexpected definitions: %v", definitions)
+	}
+
+	var user map[string]interface{}
+	if err := json.Unmarshal(files["user.json"], &user); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range collectRefs(user) {
+		if resolvePointer(user, ref) == nil {
+			t.Errorf("reference %q doesn't resolve", ref)
+		}
+	}
+
+	t.Run("unreferenced definitions are dropped", func(t *testing.T) {
+		reg := newRegistry("http://json-schema.org/draft-07/schema#")
+		order := reg.documents["order.json"]
+		order.Read(&struct{ Buyer RegistryUser }{})
+
+		var file map[string]interface{}
+		files, err := reg.Files()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(files["order.json"], &file); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := file["definitions"]; ok {
+			t.Errorf("expected no definitions, got %v", file["definitions"])
+		}
+	})
+}
+
+func TestRegistryBundle(t *testing.T) {
+	tests := []struct {
+		schema  string
+		keyword string
+	}{
+		{schema: "http://json-schema.org/draft-07/schema#", keyword: "definitions"},
+		{schema: "https://json-schema.org/draft/2020-12/schema", keyword: "$defs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			var bundle map[string]interface{}
+			out, err := newRegistry(tt.schema).Bundle()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(out, &bundle); err != nil {
+				t.Fatal(err)
+			}
+
+			definitions, _ := bundle[tt.keyword].(map[string]interface{})
+			var names []string
+			for name := range definitions {
+				names = append(names, name)
+			}
+			if diff := cmp.Diff([]string{"DefinitionAddress", "order.json", "user.json"}, sortedStrings(names)); diff != "" {
+				t.Error(diff)
+			}
+
+			refs := collectRefs(bundle)
+			if len(refs) != 4 {
+				t.Errorf("expected 4 references, got %v", refs)
+			}
+			for _, ref := range refs {
+				if !strings.HasPrefix(ref, "#/"+tt.keyword+"/") || resolvePointer(bundle, ref) == nil {
+					t.Errorf("reference %q doesn't resolve", ref)
+				}
+			}
+		})
+	}
+
+	t.Run("conflicting definitions", func(t *testing.T) {
+		other := &Document{UseDefinitions: true}
+		other.Read(&struct{ Home DefinitionAddress }{})
+		other.Definitions["DefinitionAddress"].Comment = "changed"
+
+		reg := newRegistry("http://json-schema.org/draft-07/schema#")
+		reg.Add("other.json", other)
+
+		var bundle map[string]interface{}
+		out, err := reg.Bundle()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(out, &bundle); err != nil {
+			t.Fatal(err)
+		}
+		definitions, _ := bundle["definitions"].(map[string]interface{})
+		if _, ok := definitions["other.json.DefinitionAddress"]; !ok {
+			t.Errorf("expected a qualified definition, got %v", definitions)
+		}
+		for _, ref := range collectRefs(bundle) {
+			if resolvePointer(bundle, ref) == nil {
+				t.Errorf("reference %q doesn't resolve", ref)
+			}
+		}
+	})
+}
+
+func sortedStrings(list []string) []string {
+	sorted := append([]string(nil), list...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func TestSchemaOnlyOnRoot(t *testing.T) {
+	reg := newRegistry("http://json-schema.org/draft-07/schema#")
+	user := reg.documents["user.json"]
+	user.Extensions = map[string]interface{}{"$schema": "http://json-schema.org/draft-04/schema#", "x-owner": "users"}
+	user.Definitions["DefinitionAddress"].Extensions = map[string]interface{}{"$schema": "http://json-schema.org/draft-07/schema#"}
+
+	bundle, err := reg.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := reg.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := map[string][]byte{"bundle": bundle}
+	for name, file := range files {
+		outputs[name] = file
+	}
+	for name, out := range outputs {
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(out, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded["$schema"] != "http://json-schema.org/draft-07/schema#" {
+			t.Errorf("%s: unexpected $schema %v", name, decoded["$schema"])
+		}
+		delete(decoded, "$schema")
+		if count := strings.Count(string(mustMarshal(t, decoded)), `"$schema"`); count != 0 {
+			t.Errorf("%s: expected $schema only on the root, got %s", name, out)
+		}
+	}
+	if !strings.Contains(string(outputs["user.json"]), `"x-owner": "users"`) {
+		t.Errorf("expected the other extensions to be kept, got %s", outputs["user.json"])
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
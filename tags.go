@@ -0,0 +1,426 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// schemaTag returns the tag of field holding the schema keywords, read from
+// the key SchemaTagName.
+func (d *Document) schemaTag(field reflect.StructField) string {
+	if d.SchemaTagName != "" {
+		return field.Tag.Get(d.SchemaTagName)
+	}
+
+	return field.Tag.Get("jsonschema")
+}
+
+// readSchemaTag applies the keywords of a `jsonschema:"..."` struct tag to
+// the property. Values are coerced to the kind of t, so that `default=true`
+// on a bool field is emitted as a JSON boolean; values that don't fit the
+// field's kind are left out and reported as a GenerationError.
+func (p *property) readSchemaTag(d *Document, t reflect.Type, tag string) {
+	for _, option := range parseSchemaTag(tag) {
+		switch option.key {
+		case "default":
+			if v, err := coerceValue(t, option.value); err == nil {
+				p.Default = v
+			} else {
+				d.failAt(t, invalidTagValue(option, err))
+			}
+		case "const":
+			if v, err := coerceValue(t, option.value); err == nil {
+				p.Const = v
+			} else {
+				d.failAt(t, invalidTagValue(option, err))
+			}
+		case "enum":
+			if values, err := coerceValues(t, option.values); err == nil {
+				p.Enum = values
+			} else {
+				d.failAt(t, invalidTagValue(option, err))
+			}
+		case "examples":
+			if values, err := coerceValues(t, option.values); err == nil {
+				p.Examples = values
+			} else {
+				d.failAt(t, invalidTagValue(option, err))
+			}
+		case "format":
+			format, known := normalizeFormat(option.value)
+			if !known && d.Strict {
+				d.failAt(t, fmt.Sprintf("unknown format %q", option.value))
+			}
+			p.Format = format
+		case "pattern":
+			p.Pattern = option.value
+		case "comment":
+			p.Comment = option.value
+		case "title":
+			p.Title = option.value
+		case "description":
+			p.Description = option.value
+		case "minContains", "maxContains":
+			n, err := strconv.Atoi(option.value)
+			if err == nil && n < 0 {
+				err = errNegative
+			}
+			if err != nil {
+				d.failAt(t, invalidTagValue(option, err))
+			} else if option.key == "minContains" {
+				p.MinContains = &n
+			} else {
+				p.MaxContains = &n
+			}
+		case "anyOf":
+			if derefType(t).Kind() != reflect.Interface {
+				d.failAt(t, "anyOf requires an interface field")
+				continue
+			}
+			p.AnyOf = nil
+			for _, typ := range option.values {
+				if !primitiveTypes[typ] {
+					d.failAt(t, fmt.Sprintf("invalid anyOf value %q: not a primitive type", typ))
+					continue
+				}
+				p.AnyOf = append(p.AnyOf, &property{Type: typ})
+			}
+		case "tuple":
+			if elem := derefType(t); elem.Kind() == reflect.Array {
+				p.readTuple(d, elem)
+			} else {
+				d.failAt(t, "tuple requires a fixed length array")
+			}
+		}
+	}
+}
+
+func invalidTagValue(option schemaTagOption, err error) string {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		err = numErr.Err
+	}
+
+	return fmt.Sprintf("invalid %s value %q: %v", option.key, option.value, err)
+}
+
+// errNotJSONNumber is returned when coercing NaN or infinity, which JSON
+// can't represent.
+var errNotJSONNumber = errors.New("NaN and infinity can't be represented in JSON")
+
+// errInvalidJSON is returned for object values that aren't valid JSON.
+var errInvalidJSON = errors.New("not a valid JSON object")
+
+// errNegative is returned for counts below zero.
+var errNegative = errors.New("must not be negative")
+
+// primitiveTypes holds the JSON types accepted by the anyOf tag keyword.
+var primitiveTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"null":    true,
+}
+
+// schemaTagOption is a keyword of a jsonschema tag. value is the whole
+// value with its quotes removed, values holds its pipe separated items.
+type schemaTagOption struct {
+	key    string
+	value  string
+	values []string
+}
+
+// schemaTagKeys holds the keywords understood in a jsonschema tag.
+var schemaTagKeys = map[string]bool{
+	"default":     true,
+	"const":       true,
+	"enum":        true,
+	"examples":    true,
+	"format":      true,
+	"pattern":     true,
+	"comment":     true,
+	"title":       true,
+	"description": true,
+	"tuple":       true,
+	"anyOf":       true,
+	"minContains": true,
+	"maxContains": true,
+}
+
+// parseSchemaTag splits a jsonschema tag into its comma separated keywords,
+// either `key=value` pairs or bare flags, which have an empty value. Values
+// are lists of items separated by "|".
+//
+// An item may be quoted with double or single quotes, e.g.
+// `pattern='a,b'` or `enum='a|b'|c`, to contain commas and pipes; inside
+// quotes a backslash escapes the quote character and itself. Unquoted, a
+// comma only ends a value when it is followed by a known keyword, so
+// values such as `pattern=^[a-z]{1,3}$` need no quotes either.
+func parseSchemaTag(tag string) []schemaTagOption {
+	var options []schemaTagOption
+	for i := 0; i < len(tag); i++ {
+		end := strings.IndexAny(tag[i:], "=,")
+		if end < 0 {
+			end = len(tag) - i
+		}
+		option := schemaTagOption{key: strings.TrimSpace(tag[i : i+end])}
+		i += end
+
+		if i < len(tag) && tag[i] == '=' {
+			option.value, option.values, i = scanTagValue(tag, i+1)
+		}
+		if option.key != "" {
+			options = append(options, option)
+		}
+	}
+
+	return options
+}
+
+// scanTagValue reads the value starting at tag[i] and returns it, its
+// items and the index of the comma ending it.
+func scanTagValue(tag string, i int) (string, []string, int) {
+	var value, item strings.Builder
+	var values []string
+	atItemStart := true
+	for i < len(tag) {
+		c := tag[i]
+		if atItemStart && (c == '"' || c == '\'') {
+			if quoted, end, ok := scanQuoted(tag, i); ok {
+				value.WriteString(quoted)
+				item.WriteString(quoted)
+				atItemStart = false
+				i = end
+				continue
+			}
+		}
+
+		switch {
+		case c == '|':
+			values = append(values, item.String())
+			item.Reset()
+			atItemStart = true
+		case c == ',' && startsSchemaTagOption(tag[i+1:]):
+			return value.String(), append(values, item.String()), i
+		default:
+			item.WriteByte(c)
+			atItemStart = false
+		}
+		value.WriteByte(c)
+		i++
+	}
+
+	return value.String(), append(values, item.String()), i
+}
+
+// scanQuoted reads the quoted string starting at tag[i] and returns its
+// content and the index following the closing quote. It reports false if
+// the quote isn't closed.
+func scanQuoted(tag string, i int) (string, int, bool) {
+	quote := tag[i]
+	var b strings.Builder
+	for j := i + 1; j < len(tag); j++ {
+		switch c := tag[j]; {
+		case c == quote:
+			return b.String(), j + 1, true
+		case c == '\\' && j+1 < len(tag) && (tag[j+1] == quote || tag[j+1] == '\\'):
+			j++
+			b.WriteByte(tag[j])
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", i, false
+}
+
+// startsSchemaTagOption reports whether rest, the remainder of a tag after
+// a comma, starts with a known keyword or is blank.
+func startsSchemaTagOption(rest string) bool {
+	end := strings.IndexAny(rest, "=,")
+	if end < 0 {
+		end = len(rest)
+	}
+	key := strings.TrimSpace(rest[:end])
+
+	return key == "" || schemaTagKeys[key]
+}
+
+// coerceValues coerces the items of a tag value.
+func coerceValues(t reflect.Type, items []string) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		v, err := coerceValue(t, item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// coerceValue converts a tag value to the Go value matching the kind of t,
+// dereferencing pointers, so that named types such as `type Port uint16`
+// are coerced by their underlying kind. Values of structs and maps that
+// start with "{" are parsed as JSON objects, e.g.
+// `default={"host":"localhost"}`. Other kinds without a scalar
+// representation keep the raw string.
+func coerceValue(t reflect.Type, s string) (interface{}, error) {
+	t = derefType(t)
+	if t == jsonNumberType {
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return nil, err
+		}
+		return json.Number(s), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(s, 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return nil, errNotJSONNumber
+		}
+		return f, err
+	case reflect.Struct, reflect.Map:
+		if !strings.HasPrefix(strings.TrimSpace(s), "{") {
+			return s, nil
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(s), &object); err != nil {
+			return nil, errInvalidJSON
+		}
+		return object, nil
+	default:
+		return s, nil
+	}
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// validatorFormats maps the format rules of validator tags to formats.
+var validatorFormats = map[string]string{
+	"email":    "email",
+	"url":      "uri",
+	"uri":      "uri",
+	"uuid":     "uuid",
+	"uuid4":    "uuid",
+	"hostname": "hostname",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+}
+
+// readValidatorTag applies the rules of a `validate:"..."` tag of
+// github.com/go-playground/validator that have a schema equivalent: min,
+// max, gte, lte and len bound numbers, the length of strings and the items
+// of arrays, oneof gives an enum and rules such as email a format. Rules
+// after dive apply to the items and are skipped, as are alternatives
+// separated by "|" and the rules without an equivalent. required is
+// handled by isRequired.
+func (p *property) readValidatorTag(d *Document, t reflect.Type, tag string) {
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		if key == "dive" {
+			return
+		}
+		if strings.Contains(rule, "|") {
+			continue
+		}
+		option := schemaTagOption{key: key, value: value}
+
+		switch key {
+		case "min", "gte", "max", "lte", "len":
+			if err := p.setValidatorBound(t, key, value); err != nil {
+				d.failAt(t, invalidTagValue(option, err))
+			}
+		case "oneof":
+			if values, err := coerceValues(t, strings.Fields(value)); err == nil {
+				p.Enum = values
+			} else {
+				d.failAt(t, invalidTagValue(option, err))
+			}
+		default:
+			if format, ok := validatorFormats[key]; ok {
+				p.Format = format
+			}
+		}
+	}
+}
+
+// setValidatorBound sets the bound of the validator rule key, which is the
+// value of a number, the length of a string or the number of items of an
+// array, according to the kind of t.
+func (p *property) setValidatorBound(t reflect.Type, key, value string) error {
+	lower := key == "min" || key == "gte" || key == "len"
+	upper := key == "max" || key == "lte" || key == "len"
+
+	switch derefType(t).Kind() {
+	case reflect.String, reflect.Slice, reflect.Array:
+		n, err := strconv.Atoi(value)
+		if err == nil && n < 0 {
+			err = errNegative
+		}
+		if err != nil {
+			return err
+		}
+		minimum, maximum := &p.MinItems, &p.MaxItems
+		if derefType(t).Kind() == reflect.String {
+			minimum, maximum = &p.MinLength, &p.MaxLength
+		}
+		if lower {
+			*minimum = &n
+		}
+		if upper {
+			*maximum = &n
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		v, err := coerceValue(t, value)
+		if err != nil {
+			return err
+		}
+		if lower {
+			p.Minimum = v
+		}
+		if upper {
+			p.Maximum = v
+		}
+	}
+
+	return nil
+}
+
+// hasValidatorRule reports whether the validator tag has the rule key
+// before any dive.
+func hasValidatorRule(tag, key string) bool {
+	for _, rule := range strings.Split(tag, ",") {
+		name, _, _ := strings.Cut(rule, "=")
+		if name == "dive" {
+			return false
+		}
+		if name == key {
+			return true
+		}
+	}
+
+	return false
+}
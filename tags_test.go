@@ -0,0 +1,700 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCoerceValue(t *testing.T) {
+	var (
+		ptrToBool *bool
+		anything  interface{}
+	)
+
+	tests := []struct {
+		name     string
+		t        reflect.Type
+		value    string
+		expected interface{}
+		wantErr  bool
+	}{
+		{name: "bool true", t: reflect.TypeOf(true), value: "true", expected: true},
+		{name: "bool false", t: reflect.TypeOf(true), value: "false", expected: false},
+		{name: "bool invalid", t: reflect.TypeOf(true), value: "yes please", wantErr: true},
+		{name: "pointer to bool", t: reflect.TypeOf(ptrToBool), value: "true", expected: true},
+		{name: "int", t: reflect.TypeOf(int(0)), value: "-42", expected: int64(-42)},
+		{name: "int8 overflow", t: reflect.TypeOf(int8(0)), value: "300", wantErr: true},
+		{name: "uint", t: reflect.TypeOf(uint(0)), value: "42", expected: uint64(42)},
+		{name: "uint negative", t: reflect.TypeOf(uint16(0)), value: "-1", wantErr: true},
+		{name: "float32", t: reflect.TypeOf(float32(0)), value: "1.5", expected: float64(1.5)},
+		{name: "float64", t: reflect.TypeOf(float64(0)), value: "1.699", expected: float64(1.699)},
+		{name: "float invalid", t: reflect.TypeOf(float64(0)), value: "one", wantErr: true},
+		{name: "string", t: reflect.TypeOf(""), value: "true", expected: "true"},
+		{name: "interface", t: reflect.TypeOf(&anything).Elem(), value: "1", expected: "1"},
+		{name: "struct object", t: reflect.TypeOf(struct{}{}), value: `{"host":"localhost","port":80}`, expected: map[string]interface{}{"host": "localhost", "port": float64(80)}},
+		{name: "map object", t: reflect.TypeOf(map[string]int{}), value: `{"a":1}`, expected: map[string]interface{}{"a": float64(1)}},
+		{name: "struct invalid object", t: reflect.TypeOf(struct{}{}), value: `{"host":`, wantErr: true},
+		{name: "struct raw string", t: reflect.TypeOf(struct{}{}), value: "2020-01-01", expected: "2020-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := coerceValue(tt.t, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(tt.expected, v) {
+				t.Error(cmp.Diff(tt.expected, v))
+			}
+		})
+	}
+}
+
+type ExampleJSONSchemaTag struct {
+	Enabled bool    `jsonschema:"default=true,enum=true|false"`
+	Retries int     `jsonschema:"default=3,const=3,examples=1|2|3"`
+	Port    uint16  `jsonschema:"enum=80|443"`
+	Ratio   float64 `jsonschema:"default=0.5"`
+	Mode    string  `jsonschema:"default=fast,enum=fast|slow"`
+	Broken  int     `jsonschema:"default=many"`
+}
+
+func TestReadSchemaTag(t *testing.T) {
+	j := &Document{}
+	j.Read(&ExampleJSONSchemaTag{})
+
+	expected := map[string]*property{
+		"Enabled": {Type: "boolean", Default: true, Enum: []interface{}{true, false}},
+		"Retries": {Type: "integer", Default: int64(3), Const: int64(3), Examples: []interface{}{int64(1), int64(2), int64(3)}},
+		"Port":    {Type: "integer", Enum: []interface{}{uint64(80), uint64(443)}},
+		"Ratio":   {Type: "number", Default: 0.5},
+		"Mode":    {Type: "string", Default: "fast", Enum: []interface{}{"fast", "slow"}},
+		"Broken":  {Type: "integer"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties["Enabled"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":"boolean","enum":[true,false],"default":true}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+}
+
+func TestReadDeepSchemaTag(t *testing.T) {
+	j := &Document{}
+	j.ReadDeep(&ExampleJSONSchemaTag{})
+
+	if j.Properties["Enabled"].Default != true {
+		t.Errorf("expected boolean default, got %#v", j.Properties["Enabled"].Default)
+	}
+}
+
+type ExampleJSONComment struct {
+	ID   string `jsonschema:"comment=TODO switch to uuid format"`
+	Name string
+}
+
+type ExampleJSONNoComment struct {
+	ID   string
+	Name string
+}
+
+func TestReadSchemaTagComment(t *testing.T) {
+	j := &Document{}
+	j.Read(&ExampleJSONComment{})
+
+	if j.Properties["ID"].Comment != "TODO switch to uuid format" {
+		t.Errorf("unexpected comment: %q", j.Properties["ID"].Comment)
+	}
+
+	out, err := json.Marshal(j.Properties["ID"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":"string","$comment":"TODO switch to uuid format"}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	plain := &Document{}
+	plain.Read(&ExampleJSONNoComment{})
+
+	if changes := Diff(plain, j); len(changes) != 0 {
+		t.Errorf("comment changed the schema semantics: %v", changes)
+	}
+}
+
+type ExampleJSONTitleDescription struct {
+	Port    uint16 `jsonschema:"title=Port Number,description=The TCP port to bind"`
+	Host    string `jsonschema:"description=Host name, e.g. localhost|127.0.0.1,title=Host,default=localhost"`
+	Mode    string `jsonschema:"title='Mode, fast or slow',description='Either \"fast\" or \\'slow\\'',enum=fast|slow"`
+	Comment string `jsonschema:"description=Ünïcödé: ✓ <b>&amp;</b>"`
+}
+
+func TestReadSchemaTagTitleDescription(t *testing.T) {
+	j := &Document{}
+	if err := j.TryRead(&ExampleJSONTitleDescription{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]*property{
+		"Port":    {Type: "integer", Title: "Port Number", Description: "The TCP port to bind"},
+		"Host":    {Type: "string", Title: "Host", Description: "Host name, e.g. localhost|127.0.0.1", Default: "localhost"},
+		"Mode":    {Type: "string", Title: "Mode, fast or slow", Description: `Either "fast" or 'slow'`, Enum: []interface{}{"fast", "slow"}},
+		"Comment": {Type: "string", Description: "Ünïcödé: ✓ <b>&amp;</b>"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties["Port"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"title":"Port Number","description":"The TCP port to bind","type":"integer"}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+}
+
+type ExampleJSONTuple struct {
+	Point [2]float64 `jsonschema:"tuple"`
+	Range [2]int
+}
+
+func TestReadSchemaTagTuple(t *testing.T) {
+	j := &Document{}
+	if err := j.TryRead(&ExampleJSONTuple{}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := json.Marshal(j.Properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"Point":{"type":"array","additionalItems":false,"minItems":2,"maxItems":2,"items":[{"type":"number"},{"type":"number"}]},` +
+		`"Range":{"type":"array","items":{"type":"integer"},"minItems":2,"maxItems":2}}`
+	if string(out) != expected {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	t.Run("draft 2020-12", func(t *testing.T) {
+		j := NewDocument(Draft202012)
+		if err := j.TryRead(&ExampleJSONTuple{}); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := json.Marshal(j.Properties["Point"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"type":"array","minItems":2,"maxItems":2,"prefixItems":[{"type":"number"},{"type":"number"}],"items":false}`
+		if string(out) != expected {
+			t.Errorf("unexpected JSON: %s", out)
+		}
+
+		validate := j.Validator()
+		if err := validate(map[string]interface{}{"Point": []float64{1, 2, 3}, "Range": []int{1, 2}}); err == nil {
+			t.Error("expected an error for an extra item")
+		}
+	})
+}
+
+func TestReadSchemaTagTupleOnSlice(t *testing.T) {
+	j := &Document{}
+	err := j.TryRead(&struct {
+		Point []float64 `jsonschema:"tuple"`
+	}{})
+	if err == nil || err.Error() != "jsonschema: Point: tuple requires a fixed length array ([]float64)" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCoerceValueNonFinite(t *testing.T) {
+	for _, value := range []string{"NaN", "+Inf", "-Inf", "inf"} {
+		if v, err := coerceValue(reflect.TypeOf(float64(0)), value); err == nil {
+			t.Errorf("%s: expected an error, got %v", value, v)
+		}
+	}
+}
+
+func TestReadSchemaTagNonFinite(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		message string
+	}{
+		{
+			name: "default NaN",
+			value: &struct {
+				Ratio float64 `jsonschema:"default=NaN"`
+			}{},
+			message: `jsonschema: Ratio: invalid default value "NaN": NaN and infinity can't be represented in JSON (float64)`,
+		},
+		{
+			name: "default +Inf",
+			value: &struct {
+				Ratio float32 `jsonschema:"default=+Inf"`
+			}{},
+			message: `jsonschema: Ratio: invalid default value "+Inf": NaN and infinity can't be represented in JSON (float32)`,
+		},
+		{
+			name: "examples with Inf",
+			value: &struct {
+				Ratio float64 `jsonschema:"examples=1.5|-Inf"`
+			}{},
+			message: `jsonschema: Ratio: invalid examples value "1.5|-Inf": NaN and infinity can't be represented in JSON (float64)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &Document{}
+			err := j.TryRead(tt.value)
+			if err == nil || err.Error() != tt.message {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if _, err := j.Marshal(); err != nil {
+				t.Errorf("schema must still marshal: %v", err)
+			}
+		})
+	}
+}
+
+type ExampleJSONFormatTag struct {
+	Created string `jsonschema:"format=Date-Time"`
+	Host    string `jsonschema:"format=hostname"`
+	Typo    string `jsonschema:"format=datetime"`
+}
+
+func TestReadSchemaTagFormat(t *testing.T) {
+	j := &Document{}
+	if err := j.TryRead(&ExampleJSONFormatTag{}); err != nil {
+		t.Fatalf("unknown formats must be tolerated by default: %v", err)
+	}
+
+	expected := map[string]*property{
+		"Created": {Type: "string", Format: "date-time"},
+		"Host":    {Type: "string", Format: "hostname"},
+		"Typo":    {Type: "string", Format: "datetime"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	strict := &Document{Strict: true}
+	err := strict.TryRead(&ExampleJSONFormatTag{})
+	if err == nil || err.Error() != `jsonschema: Typo: unknown format "datetime" (string)` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type ExampleJSONFormatPattern struct {
+	Email string `jsonschema:"format=email,pattern=.+@.+"`
+	Code  string `jsonschema:"pattern=^[A-Z]{2,3}$,default=ABC"`
+	Note  string `jsonschema:"comment=one, two and three"`
+}
+
+func TestReadSchemaTagPattern(t *testing.T) {
+	j := &Document{}
+	if err := j.TryRead(&ExampleJSONFormatPattern{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]*property{
+		"Email": {Type: "string", Format: "email", Pattern: ".+@.+"},
+		"Code":  {Type: "string", Pattern: "^[A-Z]{2,3}$", Default: "ABC"},
+		"Note":  {Type: "string", Comment: "one, two and three"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties["Email"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":"string","format":"email","pattern":".+@.+"}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+}
+
+func TestParseSchemaTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      string
+		expected []schemaTagOption
+	}{
+		{name: "empty", tag: "", expected: nil},
+		{name: "bare flag", tag: "tuple", expected: []schemaTagOption{{key: "tuple"}}},
+		{
+			name: "pairs",
+			tag:  "default=1,enum=1|2",
+			expected: []schemaTagOption{
+				{key: "default", value: "1", values: []string{"1"}},
+				{key: "enum", value: "1|2", values: []string{"1", "2"}},
+			},
+		},
+		{
+			name: "flag between pairs",
+			tag:  "default=1, tuple ,comment=x",
+			expected: []schemaTagOption{
+				{key: "default", value: "1", values: []string{"1"}},
+				{key: "tuple"},
+				{key: "comment", value: "x", values: []string{"x"}},
+			},
+		},
+		{
+			name: "unquoted comma",
+			tag:  "pattern=a,b,format=email",
+			expected: []schemaTagOption{
+				{key: "pattern", value: "a,b", values: []string{"a,b"}},
+				{key: "format", value: "email", values: []string{"email"}},
+			},
+		},
+		{
+			name: "trailing comma",
+			tag:  "default=3,",
+			expected: []schemaTagOption{
+				{key: "default", value: "3", values: []string{"3"}},
+			},
+		},
+		{
+			name: "empty value",
+			tag:  "default=",
+			expected: []schemaTagOption{
+				{key: "default", values: []string{""}},
+			},
+		},
+		{
+			name: "unknown key",
+			tag:  "unknown=1,default=2",
+			expected: []schemaTagOption{
+				{key: "unknown", value: "1", values: []string{"1"}},
+				{key: "default", value: "2", values: []string{"2"}},
+			},
+		},
+		{
+			name: "double quotes",
+			tag:  `pattern="a,default=b",format=email`,
+			expected: []schemaTagOption{
+				{key: "pattern", value: "a,default=b", values: []string{"a,default=b"}},
+				{key: "format", value: "email", values: []string{"email"}},
+			},
+		},
+		{
+			name: "single quotes",
+			tag:  `comment='it, too',default=1`,
+			expected: []schemaTagOption{
+				{key: "comment", value: "it, too", values: []string{"it, too"}},
+				{key: "default", value: "1", values: []string{"1"}},
+			},
+		},
+		{
+			name: "quoted list items",
+			tag:  `enum='a|b'|c|"d,e"`,
+			expected: []schemaTagOption{
+				{key: "enum", value: "a|b|c|d,e", values: []string{"a|b", "c", "d,e"}},
+			},
+		},
+		{
+			name: "escapes",
+			tag:  `pattern='it\'s\\d\w'`,
+			expected: []schemaTagOption{
+				{key: "pattern", value: `it's\d\w`, values: []string{`it's\d\w`}},
+			},
+		},
+		{
+			name: "quotes inside values",
+			tag:  `comment=don't "panic"`,
+			expected: []schemaTagOption{
+				{key: "comment", value: `don't "panic"`, values: []string{`don't "panic"`}},
+			},
+		},
+		{
+			name: "unterminated quote",
+			tag:  `comment='open,default=1`,
+			expected: []schemaTagOption{
+				{key: "comment", value: "'open", values: []string{"'open"}},
+				{key: "default", value: "1", values: []string{"1"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, parseSchemaTag(tt.tag), cmp.AllowUnexported(schemaTagOption{})); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+type ExampleJSONQuotedTag struct {
+	Code  string `jsonschema:"pattern='^[a-z]+(,[a-z]+)*$',default='a,b'"`
+	Modes string `jsonschema:"enum='read|write'|admin"`
+}
+
+func TestReadSchemaTagQuoted(t *testing.T) {
+	j := &Document{}
+	if err := j.TryRead(&ExampleJSONQuotedTag{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]*property{
+		"Code":  {Type: "string", Pattern: "^[a-z]+(,[a-z]+)*$", Default: "a,b"},
+		"Modes": {Type: "string", Enum: []interface{}{"read|write", "admin"}},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type ExampleJSONAnyOfTag struct {
+	Value interface{} `json:"value" jsonschema:"anyOf=string|number|boolean"`
+}
+
+func TestReadSchemaTagAnyOf(t *testing.T) {
+	j := &Document{}
+	if err := j.TryRead(&ExampleJSONAnyOfTag{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &property{AnyOf: []*property{{Type: "string"}, {Type: "number"}, {Type: "boolean"}}}
+	if diff := cmp.Diff(expected, j.Properties["value"]); diff != "" {
+		t.Error(diff)
+	}
+	out, err := json.Marshal(j.Properties["value"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"anyOf":[{"type":"string"},{"type":"number"},{"type":"boolean"}]}` {
+		t.Errorf("unexpected encoding %s", out)
+	}
+
+	validate := j.Validator()
+	for _, value := range []interface{}{"a", 1.5, true} {
+		if err := validate(ExampleJSONAnyOfTag{Value: value}); err != nil {
+			t.Errorf("%v: unexpected error: %v", value, err)
+		}
+	}
+	if err := validate(ExampleJSONAnyOfTag{Value: []int{1}}); err == nil {
+		t.Error("expected an error for an array")
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			value    interface{}
+			expected string
+		}{
+			{"not an interface", &struct {
+				Value string `jsonschema:"anyOf=string|number"`
+			}{}, "anyOf requires an interface field"},
+			{"not a primitive", &struct {
+				Value interface{} `jsonschema:"anyOf=string|object"`
+			}{}, `invalid anyOf value "object": not a primitive type`},
+		}
+		for _, tt := range tests {
+			var genErr *GenerationError
+			if err := (&Document{}).TryRead(tt.value); !errors.As(err, &genErr) || genErr.Reason != tt.expected {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+		}
+	})
+}
+
+type TagServer struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type ExampleJSONObjectDefault struct {
+	Server TagServer `json:"server" jsonschema:"default={\"host\":\"localhost\",\"port\":8080}"`
+}
+
+func TestReadSchemaTagObjectDefault(t *testing.T) {
+	j := &Document{}
+	if err := j.TryRead(&ExampleJSONObjectDefault{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{"host": "localhost", "port": float64(8080)}
+	if diff := cmp.Diff(expected, j.Properties["server"].Default); diff != "" {
+		t.Error(diff)
+	}
+
+	err := j.TryRead(&struct {
+		Server TagServer `jsonschema:"default={\"host\":}"`
+	}{})
+	var genErr *GenerationError
+	if !errors.As(err, &genErr) || genErr.Reason != `invalid default value "{\"host\":}": not a valid JSON object` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+type ExampleJSONSchemaTagName struct {
+	Mode  string `schema:"enum=fast|slow,default=fast"`
+	Other string `jsonschema:"default=ignored"`
+}
+
+func TestSchemaTagName(t *testing.T) {
+	j := &Document{SchemaTagName: "schema"}
+	if err := j.TryRead(&ExampleJSONSchemaTagName{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]*property{
+		"Mode":  {Type: "string", Enum: []interface{}{"fast", "slow"}, Default: "fast"},
+		"Other": {Type: "string"},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{SchemaTagName: "schema"}
+	deep.ReadDeep(&ExampleJSONSchemaTagName{})
+	if diff := cmp.Diff(expected, deep.Properties); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type ExampleJSONValidatorTags struct {
+	Name     string   `json:"name" validate:"required,min=1,max=100"`
+	Email    string   `json:"email,omitempty" validate:"required,email"`
+	Age      uint8    `json:"age,omitempty" validate:"gte=18,lte=130"`
+	Ratio    float64  `json:"ratio" validate:"min=0,max=1"`
+	Code     string   `json:"code" validate:"len=4"`
+	Tags     []string `json:"tags" validate:"max=5,dive,min=2"`
+	Role     string   `json:"role" validate:"oneof=admin user"`
+	Website  string   `json:"website,omitempty" validate:"omitempty,url|uri"`
+	Priority int      `json:"priority" validate:"min=1" jsonschema:"default=3"`
+	Country  string   `json:"country" validate:"iso3166_1_alpha2"`
+}
+
+func TestValidatorTags(t *testing.T) {
+	one, four, five, hundred := 1, 4, 5, 100
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"name":     {Type: "string", MinLength: &one, MaxLength: &hundred},
+			"email":    {Type: "string", Format: "email"},
+			"age":      {Type: "integer", Minimum: uint64(18), Maximum: uint64(130)},
+			"ratio":    {Type: "number", Minimum: float64(0), Maximum: float64(1)},
+			"code":     {Type: "string", MinLength: &four, MaxLength: &four},
+			"tags":     {Type: "array", Items: &property{Type: "string"}, MaxItems: &five},
+			"role":     {Type: "string", Enum: []interface{}{"admin", "user"}},
+			"website":  {Type: "string"},
+			"priority": {Type: "integer", Minimum: int64(1), Default: int64(3)},
+			"country":  {Type: "string"},
+		},
+		Required: []string{"name", "email", "ratio", "code", "tags", "role", "priority", "country"},
+	}
+
+	j := &Document{UseValidatorTags: true}
+	if err := j.TryRead(&ExampleJSONValidatorTags{}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	deep := &Document{UseValidatorTags: true}
+	if err := deep.TryReadDeep(&ExampleJSONValidatorTags{}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, deep.property); diff != "" {
+		t.Error(diff)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONValidatorTags{})
+		if p := j.Properties["name"]; p.MinLength != nil || p.MaxLength != nil {
+			t.Errorf("expected no length bounds, got %+v", p)
+		}
+		if containsString(j.Required, "email") {
+			t.Error("expected email to be optional")
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		j := &Document{UseValidatorTags: true}
+		err := j.TryRead(&struct {
+			Count uint `validate:"min=-1"`
+		}{})
+
+		var genErr *GenerationError
+		if !errors.As(err, &genErr) || genErr.Reason != `invalid min value "-1": invalid syntax` {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+	t.Run("validation", func(t *testing.T) {
+		validate := j.Validator()
+		value := ExampleJSONValidatorTags{Name: "a", Email: "a@b.c", Ratio: 0.5, Code: "abcd", Tags: []string{}, Role: "user", Priority: 1}
+		if err := validate(value); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		value.Code = "abc"
+		if err := validate(value); err == nil || err.Error() != "jsonschema: code: expected at least 4 characters, got 3" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+type TagPort uint16
+
+type TagRatio float32
+
+type ExampleJSONNamedNumbers struct {
+	Port    TagPort   `json:"port" jsonschema:"default=8080,enum=80|443|8080"`
+	Backup  *TagPort  `json:"backup,omitempty" jsonschema:"default=8443"`
+	Ratio   TagRatio  `json:"ratio" jsonschema:"default=0.5"`
+	Unknown []TagPort `json:"unknown,omitempty"`
+}
+
+func TestReadSchemaTagNamedNumbers(t *testing.T) {
+	j := &Document{}
+	if err := j.TryRead(&ExampleJSONNamedNumbers{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]*property{
+		"port":    {Type: "integer", Default: uint64(8080), Enum: []interface{}{uint64(80), uint64(443), uint64(8080)}},
+		"backup":  {Type: "integer", Default: uint64(8443)},
+		"ratio":   {Type: "number", Default: float64(0.5)},
+		"unknown": {Type: "array", Items: &property{Type: "integer"}},
+	}
+	if diff := cmp.Diff(expected, j.Properties); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.Properties["port"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":"integer","enum":[80,443,8080],"default":8080}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	err = j.TryRead(&struct {
+		Port TagPort `jsonschema:"default=70000"`
+	}{})
+	var genErr *GenerationError
+	if !errors.As(err, &genErr) || genErr.Reason != `invalid default value "70000": value out of range` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package jsonschema
+
+import (
+	"reflect"
+	"sort"
+)
+
+// DiscriminatedUnion describes a tagged union whose variant is chosen by the
+// string field fieldName, e.g. "type". variants maps each discriminator
+// value to a sample of its variant struct. The root object gets the
+// discriminator as a required property limited to the variant values, and
+// one allOf branch per variant that applies the variant's schema, including
+// its required fields, if the discriminator has that value. Calling it again
+// for the same fieldName replaces those branches. It returns the first
+// problem found while reading the variants, like TryRead.
+func (d *Document) DiscriminatedUnion(fieldName string, variants map[string]interface{}) error {
+	values := make([]string, 0, len(variants))
+	for value := range variants {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	d.err = nil
+	d.setDefaultSchema()
+	if d.Type == "" {
+		d.Type = "object"
+	}
+	if d.Properties == nil {
+		d.Properties = make(map[string]*property)
+	}
+	discriminator := &property{Type: "string"}
+	for _, value := range values {
+		discriminator.Enum = append(discriminator.Enum, value)
+	}
+	d.Properties[fieldName] = discriminator
+	if !containsString(d.Required, fieldName) {
+		d.Required = append(d.Required, fieldName)
+	}
+
+	var allOf []*property
+	for _, branch := range d.AllOf {
+		if !isUnionBranch(branch, fieldName) {
+			allOf = append(allOf, branch)
+		}
+	}
+	d.AllOf = allOf
+
+	for _, value := range values {
+		parent := d.path
+		d.path = joinPath(parent, fieldName+"="+value)
+		variant := &property{}
+		variant.read(d, reflect.TypeOf(variants[value]), "")
+		d.path = parent
+
+		d.AllOf = append(d.AllOf, &property{
+			If: &property{
+				Properties: map[string]*property{fieldName: {Const: value}},
+				Required:   []string{fieldName},
+			},
+			Then: variant,
+		})
+	}
+	d.finishRead()
+
+	return d.err
+}
+
+// isUnionBranch reports whether branch is one added by DiscriminatedUnion
+// for the discriminator fieldName.
+func isUnionBranch(branch *property, fieldName string) bool {
+	if branch.If == nil || branch.Then == nil {
+		return false
+	}
+	discriminator := branch.If.Properties[fieldName]
+
+	return discriminator != nil && discriminator.Const != nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
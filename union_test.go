@@ -0,0 +1,91 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type UnionCircle struct {
+	Kind   string  `json:"kind"`
+	Radius float64 `json:"radius"`
+}
+
+type UnionSquare struct {
+	Kind  string  `json:"kind"`
+	Side  float64 `json:"side"`
+	Label string  `json:"label,omitempty"`
+}
+
+func TestDiscriminatedUnion(t *testing.T) {
+	j := &Document{}
+	j.DiscriminatedUnion("kind", map[string]interface{}{
+		"square": UnionSquare{},
+		"circle": &UnionCircle{},
+	})
+
+	branch := func(value string, then *property) *property {
+		return &property{
+			If: &property{
+				Properties: map[string]*property{"kind": {Const: value}},
+				Required:   []string{"kind"},
+			},
+			Then: then,
+		}
+	}
+	expected := property{
+		Type: "object",
+		Properties: map[string]*property{
+			"kind": {Type: "string", Enum: []interface{}{"circle", "square"}},
+		},
+		Required: []string{"kind"},
+		AllOf: []*property{
+			branch("circle", &property{
+				Type: "object",
+				Properties: map[string]*property{
+					"kind":   {Type: "string"},
+					"radius": {Type: "number"},
+				},
+				Required: []string{"kind", "radius"},
+			}),
+			branch("square", &property{
+				Type: "object",
+				Properties: map[string]*property{
+					"kind":  {Type: "string"},
+					"side":  {Type: "number"},
+					"label": {Type: "string"},
+				},
+				Required: []string{"kind", "side"},
+			}),
+		},
+	}
+	if diff := cmp.Diff(expected, j.property); diff != "" {
+		t.Error(diff)
+	}
+
+	out, err := json.Marshal(j.AllOf[0].If)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"properties":{"kind":{"const":"circle"}},"required":["kind"]}` {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	t.Run("repeated call", func(t *testing.T) {
+		other := &property{Comment: "kept"}
+		j.AllOf = append(j.AllOf, other)
+		err := j.DiscriminatedUnion("kind", map[string]interface{}{
+			"square": UnionSquare{},
+			"circle": &UnionCircle{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected.AllOf = []*property{other, expected.AllOf[0], expected.AllOf[1]}
+		if diff := cmp.Diff(expected, j.property); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
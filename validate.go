@@ -0,0 +1,518 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError describes why a value doesn't match a Document. Path is
+// the dotted path of the offending value, with "[i]" denoting array
+// elements, and is empty for the root.
+type ValidationError struct {
+	Path   string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return "jsonschema: " + e.Reason
+	}
+
+	return fmt.Sprintf("jsonschema: %s: %s", e.Path, e.Reason)
+}
+
+// validator checks a decoded JSON value found at path.
+type validator func(path string, v interface{}) error
+
+// Validator returns a function that checks values against the Document,
+// without a separate validation library. Values are encoded as JSON first,
+// so Go values are checked the way they are marshalled. It covers the
+// keywords generated by this package, such as type, required, enum,
+// const, minimum and maximum, the string length, pattern, the array
+// bounds, references to definitions and the combinations with allOf,
+// anyOf, oneOf and if. Of the formats, only regex is checked, by compiling
+// the value. The first mismatch is returned as a ValidationError.
+func (d *Document) Validator() func(interface{}) error {
+	root := d.property.clone()
+	c := &compiler{prefix: d.refPrefix(), definitions: make(map[string]validator, len(root.Definitions))}
+	for name, definition := range root.Definitions {
+		c.definitions[c.prefix+name] = c.compile(definition)
+	}
+	validate := c.compile(root)
+
+	return func(value interface{}) error {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var decoded interface{}
+		if err := dec.Decode(&decoded); err != nil {
+			return err
+		}
+		if c.err != nil {
+			return c.err
+		}
+
+		return validate("", decoded)
+	}
+}
+
+// compiler turns properties into validators. References are looked up
+// when validating, so that recursive definitions can be compiled.
+type compiler struct {
+	prefix      string
+	definitions map[string]validator
+	err         error
+}
+
+func (c *compiler) compile(p *property) validator {
+	if p.isNullableSchema() {
+		schema := *p
+		schema.Nullable = false
+		check := c.compile(&schema)
+		return func(path string, v interface{}) error {
+			if v == nil {
+				return nil
+			}
+			return check(path, v)
+		}
+	}
+
+	var checks []validator
+
+	if p.Ref != "" {
+		checks = append(checks, c.compileRef(p.Ref))
+	}
+	if p.Type != "" {
+		types := []string{p.Type}
+		if p.Nullable {
+			types = append(types, "null")
+		}
+		checks = append(checks, func(path string, v interface{}) error {
+			for _, typ := range types {
+				if hasType(v, typ) {
+					return nil
+				}
+			}
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), typeOf(v))}
+		})
+	}
+	if p.Enum != nil {
+		enum := normalizeValues(p.Enum)
+		if p.Nullable && p.Type != "" {
+			enum = append(enum, nil)
+		}
+		checks = append(checks, func(path string, v interface{}) error {
+			for _, value := range enum {
+				if equalValues(value, v) {
+					return nil
+				}
+			}
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("value %s is not one of the enum", encodeValue(v))}
+		})
+	}
+	if p.Const != nil {
+		value := normalizeValues([]interface{}{p.Const})[0]
+		checks = append(checks, func(path string, v interface{}) error {
+			if !equalValues(value, v) {
+				return &ValidationError{Path: path, Reason: fmt.Sprintf("expected %s, got %s", encodeValue(value), encodeValue(v))}
+			}
+			return nil
+		})
+	}
+	if p.Minimum != nil || p.Maximum != nil {
+		checks = append(checks, compileRange(p.Minimum, p.Maximum))
+	}
+	if p.MinLength != nil || p.MaxLength != nil {
+		checks = append(checks, compileLength(p.MinLength, p.MaxLength))
+	}
+	if p.Pattern != "" {
+		checks = append(checks, c.compilePattern(p.Pattern))
+	}
+	if p.Format == "regex" {
+		checks = append(checks, checkRegex)
+	}
+	if p.Properties != nil || p.Required != nil || p.DependentSchemas != nil || p.PropertyNames != nil || p.AdditionalSchema != nil {
+		checks = append(checks, c.compileObject(p))
+	}
+	if p.Items != nil || p.TupleItems != nil || p.MinItems != nil || p.MaxItems != nil || p.Contains != nil {
+		checks = append(checks, c.compileArray(p))
+	}
+	for _, branch := range p.AllOf {
+		checks = append(checks, c.compile(branch))
+	}
+	if p.AnyOf != nil {
+		checks = append(checks, c.compileAnyOf(p.AnyOf))
+	}
+	if p.OneOf != nil {
+		checks = append(checks, c.compileOneOf(p.OneOf))
+	}
+	if p.If != nil && p.Then != nil {
+		condition, then := c.compile(p.If), c.compile(p.Then)
+		checks = append(checks, func(path string, v interface{}) error {
+			if condition(path, v) != nil {
+				return nil
+			}
+			return then(path, v)
+		})
+	}
+
+	return func(path string, v interface{}) error {
+		for _, check := range checks {
+			if err := check(path, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (c *compiler) compileRef(ref string) validator {
+	return func(path string, v interface{}) error {
+		validate, ok := c.definitions[ref]
+		if !ok {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("unresolved reference %q", ref)}
+		}
+		return validate(path, v)
+	}
+}
+
+func (c *compiler) compilePattern(pattern string) validator {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		if c.err == nil {
+			c.err = fmt.Errorf("jsonschema: invalid pattern %q: %w", pattern, err)
+		}
+		return func(string, interface{}) error { return nil }
+	}
+
+	return func(path string, v interface{}) error {
+		if s, ok := v.(string); ok && !re.MatchString(s) {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("%q doesn't match %q", s, pattern)}
+		}
+		return nil
+	}
+}
+
+// checkRegex checks that a string with the regex format compiles. Go's
+// syntax is used, which lacks some ECMA-262 features such as lookarounds.
+func checkRegex(path string, v interface{}) error {
+	s, ok := v.(string)
+	if !ok {
+		return nil
+	}
+	if _, err := regexp.Compile(s); err != nil {
+		return &ValidationError{Path: path, Reason: fmt.Sprintf("%q is not a valid regular expression", s)}
+	}
+	return nil
+}
+
+// compileObject checks the members of an object. The ".*" property, which
+// this package generates for the values of maps, or else the
+// additionalProperties schema applies to every member without a property
+// of its own.
+func (c *compiler) compileObject(p *property) validator {
+	properties := make(map[string]validator, len(p.Properties))
+	for name, child := range p.Properties {
+		properties[name] = c.compile(child)
+	}
+	dependents := make(map[string]validator, len(p.DependentSchemas))
+	for name, child := range p.DependentSchemas {
+		dependents[name] = c.compile(child)
+	}
+	var names, additional validator
+	if p.PropertyNames != nil {
+		names = c.compile(p.PropertyNames)
+	}
+	if p.AdditionalSchema != nil {
+		additional = c.compile(p.AdditionalSchema)
+	}
+	required := p.Required
+
+	return func(path string, v interface{}) error {
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, name := range required {
+			if _, ok := object[name]; !ok {
+				return &ValidationError{Path: path, Reason: fmt.Sprintf("missing required property %q", name)}
+			}
+		}
+		for _, name := range sortedValueKeys(object) {
+			if names != nil {
+				if err := names(joinPath(path, name), name); err != nil {
+					return err
+				}
+			}
+			validate, ok := properties[name]
+			if !ok {
+				validate, ok = properties[".*"]
+			}
+			if !ok && additional != nil {
+				validate, ok = additional, true
+			}
+			if ok {
+				if err := validate(joinPath(path, name), object[name]); err != nil {
+					return err
+				}
+			}
+			if dependent, ok := dependents[name]; ok {
+				if err := dependent(path, v); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func (c *compiler) compileArray(p *property) validator {
+	var items validator
+	if p.Items != nil {
+		items = c.compile(p.Items)
+	}
+	var tuple []validator
+	for _, item := range p.TupleItems {
+		tuple = append(tuple, c.compile(item))
+	}
+	var contains validator
+	if p.Contains != nil {
+		contains = c.compile(p.Contains)
+	}
+	minItems, maxItems := p.MinItems, p.MaxItems
+	closed := p.AdditionalItems != nil && !*p.AdditionalItems
+	minContains, maxContains := 1, -1
+	if p.MinContains != nil {
+		minContains = *p.MinContains
+	}
+	if p.MaxContains != nil {
+		maxContains = *p.MaxContains
+	}
+
+	return func(path string, v interface{}) error {
+		array, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+		switch {
+		case minItems != nil && len(array) < *minItems:
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("expected at least %d items, got %d", *minItems, len(array))}
+		case maxItems != nil && len(array) > *maxItems:
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("expected at most %d items, got %d", *maxItems, len(array))}
+		case closed && len(array) > len(tuple):
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("expected at most %d items, got %d", len(tuple), len(array))}
+		}
+
+		matches := 0
+		for i, item := range array {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			validate := items
+			if tuple != nil {
+				validate = nil
+				if i < len(tuple) {
+					validate = tuple[i]
+				}
+			}
+			if validate != nil {
+				if err := validate(itemPath, item); err != nil {
+					return err
+				}
+			}
+			if contains != nil && contains(itemPath, item) == nil {
+				matches++
+			}
+		}
+		if contains != nil && (matches < minContains || maxContains >= 0 && matches > maxContains) {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("%d items match contains", matches)}
+		}
+		return nil
+	}
+}
+
+func (c *compiler) compileAnyOf(branches []*property) validator {
+	validators := make([]validator, len(branches))
+	for i, branch := range branches {
+		validators[i] = c.compile(branch)
+	}
+
+	return func(path string, v interface{}) error {
+		for _, validate := range validators {
+			if validate(path, v) == nil {
+				return nil
+			}
+		}
+		return &ValidationError{Path: path, Reason: "expected a match of anyOf"}
+	}
+}
+
+func (c *compiler) compileOneOf(branches []*property) validator {
+	validators := make([]validator, len(branches))
+	for i, branch := range branches {
+		validators[i] = c.compile(branch)
+	}
+
+	return func(path string, v interface{}) error {
+		matches := 0
+		for _, validate := range validators {
+			if validate(path, v) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("expected exactly one oneOf match, got %d", matches)}
+		}
+		return nil
+	}
+}
+
+func compileRange(minimum, maximum interface{}) validator {
+	min, max := toRat(minimum), toRat(maximum)
+
+	return func(path string, v interface{}) error {
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil
+		}
+		value := toRat(n)
+		switch {
+		case value == nil:
+			return nil
+		case min != nil && value.Cmp(min) < 0:
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("%s is less than the minimum %s", n, min.RatString())}
+		case max != nil && value.Cmp(max) > 0:
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("%s is greater than the maximum %s", n, max.RatString())}
+		}
+		return nil
+	}
+}
+
+func compileLength(minLength, maxLength *int) validator {
+	return func(path string, v interface{}) error {
+		s, ok := v.(string)
+		if !ok {
+			return nil
+		}
+		n := utf8.RuneCountInString(s)
+		switch {
+		case minLength != nil && n < *minLength:
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("expected at least %d characters, got %d", *minLength, n)}
+		case maxLength != nil && n > *maxLength:
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("expected at most %d characters, got %d", *maxLength, n)}
+		}
+		return nil
+	}
+}
+
+// toRat returns a number as an exact rational, or nil if v isn't one.
+func toRat(v interface{}) *big.Rat {
+	if v == nil {
+		return nil
+	}
+	r, ok := new(big.Rat).SetString(fmt.Sprint(v))
+	if !ok {
+		return nil
+	}
+
+	return r
+}
+
+// hasType reports whether the decoded JSON value v is of the JSON Schema
+// type typ.
+func hasType(v interface{}, typ string) bool {
+	switch typ {
+	case "integer":
+		r := toRat(v)
+		_, ok := v.(json.Number)
+		return ok && r != nil && r.IsInt()
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	default:
+		return typeOf(v) == typ
+	}
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// normalizeValues converts Go values to their decoded JSON form, so that
+// they can be compared with the values being validated.
+func normalizeValues(values []interface{}) []interface{} {
+	normalized := make([]interface{}, len(values))
+	for i, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		_ = dec.Decode(&normalized[i])
+	}
+
+	return normalized
+}
+
+// equalValues compares decoded JSON values, numbers by their value.
+func equalValues(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		ra, rb := toRat(a), toRat(b)
+		return ok && ra != nil && rb != nil && ra.Cmp(rb) == 0
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalValues(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			if other, ok := b[key]; !ok || !equalValues(value, other) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func encodeValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(data)
+}
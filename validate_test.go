@@ -0,0 +1,144 @@
+package jsonschema
+
+import (
+	"errors"
+	"testing"
+)
+
+type ValidatedAddress struct {
+	Street string `json:"street" jsonschema:"pattern=^[A-Z]"`
+	Zip    string `json:"zip,omitempty"`
+}
+
+type ValidatedUser struct {
+	Name     string              `json:"name"`
+	Age      uint8               `json:"age"`
+	Status   Status              `json:"status"`
+	Home     ValidatedAddress    `json:"home"`
+	Previous []ValidatedAddress  `json:"previous,omitempty"`
+	Scores   map[string]float64  `json:"scores,omitempty"`
+	Point    [2]int              `json:"point" jsonschema:"tuple"`
+	Nickname *string             `json:"nickname,omitempty"`
+	Labels   map[string][]string `json:"labels,omitempty"`
+}
+
+func TestValidator(t *testing.T) {
+	for _, useDefinitions := range []bool{false, true} {
+		j := &Document{InferIntegerBounds: true, OmitemptyAsNullable: true, UseDefinitions: useDefinitions}
+		j.RegisterStringEnum(Status(""), "active", "inactive")
+		if err := j.TryRead(&ValidatedUser{}); err != nil {
+			t.Fatal(err)
+		}
+		validate := j.Validator()
+
+		valid := ValidatedUser{Name: "a", Age: 30, Status: "active", Home: ValidatedAddress{Street: "Main"}}
+		if err := validate(valid); err != nil {
+			t.Errorf("unexpected error for a valid user: %v", err)
+		}
+		if err := validate(&valid); err != nil {
+			t.Errorf("unexpected error for a pointer to a valid user: %v", err)
+		}
+
+		tests := []struct {
+			name     string
+			value    interface{}
+			expected string
+		}{
+			{"type", map[string]interface{}{"name": 1, "age": 3, "status": "active", "home": map[string]interface{}{"street": "A"}, "point": []int{1, 2}}, `jsonschema: name: expected string, got number`},
+			{"required", map[string]interface{}{"name": "a"}, `jsonschema: missing required property "age"`},
+			{"enum", func() ValidatedUser { u := valid; u.Status = "gone"; return u }(), `jsonschema: status: value "gone" is not one of the enum`},
+			{"pattern", func() ValidatedUser { u := valid; u.Home.Street = "main"; return u }(), `jsonschema: home.street: "main" doesn't match "^[A-Z]"`},
+			{"integer", map[string]interface{}{"name": "a", "age": 1.5, "status": "active", "home": map[string]interface{}{"street": "A"}, "point": []int{1, 2}}, `jsonschema: age: expected integer, got number`},
+			{"maximum", map[string]interface{}{"name": "a", "age": 300, "status": "active", "home": map[string]interface{}{"street": "A"}, "point": []int{1, 2}}, `jsonschema: age: 300 is greater than the maximum 255`},
+			{"tuple", map[string]interface{}{"name": "a", "age": 3, "status": "active", "home": map[string]interface{}{"street": "A"}, "point": []int{1, 2, 3}}, `jsonschema: point: expected at most 2 items, got 3`},
+			{"map values", func() ValidatedUser { u := valid; u.Labels = map[string][]string{"a": {"x"}}; u.Scores = nil; return u }(), ``},
+			{"map value type", map[string]interface{}{"name": "a", "age": 3, "status": "active", "home": map[string]interface{}{"street": "A"}, "point": []int{1, 2}, "scores": map[string]interface{}{"x": "high"}}, `jsonschema: scores.x: expected number, got string`},
+			{"nullable", map[string]interface{}{"name": "a", "age": 3, "status": "active", "home": map[string]interface{}{"street": "A"}, "point": []int{1, 2}, "nickname": nil}, ``},
+			{"nested", func() ValidatedUser {
+				u := valid
+				u.Previous = []ValidatedAddress{{Street: "A"}, {Street: "b"}}
+				return u
+			}(), `jsonschema: previous[1].street: "b" doesn't match "^[A-Z]"`},
+		}
+		for _, tt := range tests {
+			err := validate(tt.value)
+			switch {
+			case tt.expected == "" && err != nil:
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			case tt.expected != "" && (err == nil || err.Error() != tt.expected):
+				t.Errorf("%s: expected %q, got %v", tt.name, tt.expected, err)
+			}
+		}
+
+		var validationErr *ValidationError
+		if err := validate(map[string]interface{}{}); !errors.As(err, &validationErr) || validationErr.Path != "" {
+			t.Errorf("expected a ValidationError, got %v", err)
+		}
+	}
+}
+
+func TestValidatorCombinations(t *testing.T) {
+	t.Run("oneOf", func(t *testing.T) {
+		j := &Document{}
+		j.RegisterImplementations((*Shape)(nil), Circle{}, &Square{})
+		j.Read(&struct{ Shape Shape }{})
+		validate := j.Validator()
+
+		if err := validate(map[string]interface{}{"Shape": map[string]interface{}{"radius": 1}}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		err := validate(map[string]interface{}{"Shape": map[string]interface{}{"radius": 1, "side": 2}})
+		if err == nil || err.Error() != "jsonschema: Shape: expected exactly one oneOf match, got 2" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("contains", func(t *testing.T) {
+		j := NewDocument(Draft202012)
+		j.SetContains("members", &property{Properties: map[string]*property{"role": {Const: "admin"}}, Required: []string{"role"}})
+		j.Read(&struct {
+			Members []ContainsUser `json:"members" jsonschema:"minContains=2"`
+		}{})
+		validate := j.Validator()
+
+		admin := ContainsUser{Name: "a", Role: "admin"}
+		if err := validate(map[string]interface{}{"members": []ContainsUser{admin, admin}}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		err := validate(map[string]interface{}{"members": []ContainsUser{admin, {Name: "b", Role: "user"}}})
+		if err == nil || err.Error() != "jsonschema: members: 1 items match contains" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("invalid pattern", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&struct {
+			Name string `jsonschema:"pattern=(?<=a)b"`
+		}{})
+
+		if err := j.Validator()(map[string]interface{}{"Name": "b"}); err == nil {
+			t.Error("expected an error for an invalid pattern")
+		}
+	})
+}
+
+func TestValidatorRegexFormat(t *testing.T) {
+	type Rule struct {
+		Match string `json:"match" jsonschema:"format=regex"`
+	}
+
+	j := &Document{Strict: true}
+	if err := j.TryRead(&Rule{}); err != nil {
+		t.Fatal(err)
+	}
+	if format := j.Properties["match"].Format; format != "regex" {
+		t.Errorf("expected the regex format, got %q", format)
+	}
+
+	validate := j.Validator()
+	if err := validate(Rule{Match: `^[a-z]+\d*$`}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validate(Rule{Match: "[a-"}); err == nil || err.Error() != `jsonschema: match: "[a-" is not a valid regular expression` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package jsonschema
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Walk calls fn for the root of the Document and every property below it,
+// depth first and in a stable order. The path of a property is the dotted
+// path of its name, with "[]" for array items, "[i]" for tuple items,
+// "contains", "propertyNames", "additionalProperties", "allOf[i]",
+// "anyOf[i]", "oneOf[i]", "if" and "then" for the subschemas of those
+// keywords and "dependentSchemas.Name" and "definitions.Name" for the
+// members of those; the root has the empty path.
+func (d *Document) Walk(fn func(path string, p *Schema)) {
+	d.property.walk("", fn)
+}
+
+func (p *property) walk(path string, fn func(path string, p *property)) {
+	fn(path, p)
+	p.forEachChildPath(path, func(childPath string, child *property) {
+		child.walk(childPath, fn)
+	})
+}
+
+// forEachChildPath calls fn with each direct subschema of p and its path.
+func (p *property) forEachChildPath(path string, fn func(path string, child *property)) {
+	for _, name := range sortedKeys(p.Properties) {
+		fn(joinPath(path, name), p.Properties[name])
+	}
+	for _, name := range sortedKeys(p.DependentSchemas) {
+		fn(joinPath(joinPath(path, "dependentSchemas"), name), p.DependentSchemas[name])
+	}
+	if p.Items != nil {
+		fn(path+"[]", p.Items)
+	}
+	if p.Contains != nil {
+		fn(joinPath(path, "contains"), p.Contains)
+	}
+	if p.PropertyNames != nil {
+		fn(joinPath(path, "propertyNames"), p.PropertyNames)
+	}
+	if p.AdditionalSchema != nil {
+		fn(joinPath(path, "additionalProperties"), p.AdditionalSchema)
+	}
+	for i, item := range p.TupleItems {
+		fn(fmt.Sprintf("%s[%d]", path, i), item)
+	}
+	for i, branch := range p.AllOf {
+		fn(joinPath(path, fmt.Sprintf("allOf[%d]", i)), branch)
+	}
+	for i, branch := range p.AnyOf {
+		fn(joinPath(path, fmt.Sprintf("anyOf[%d]", i)), branch)
+	}
+	for i, branch := range p.OneOf {
+		fn(joinPath(path, fmt.Sprintf("oneOf[%d]", i)), branch)
+	}
+	if p.If != nil {
+		fn(joinPath(path, "if"), p.If)
+	}
+	if p.Then != nil {
+		fn(joinPath(path, "then"), p.Then)
+	}
+	for _, name := range sortedKeys(p.Definitions) {
+		fn(joinPath(joinPath(path, "definitions"), name), p.Definitions[name])
+	}
+}
+
+// forEachChild calls fn with each direct subschema of p, in no particular
+// order. It skips the path bookkeeping of forEachChildPath.
+func (p *property) forEachChild(fn func(*property)) {
+	for _, child := range p.Properties {
+		fn(child)
+	}
+	for _, child := range p.DependentSchemas {
+		fn(child)
+	}
+	for _, child := range []*property{p.Items, p.Contains, p.PropertyNames, p.AdditionalSchema, p.If, p.Then} {
+		if child != nil {
+			fn(child)
+		}
+	}
+	for _, child := range p.TupleItems {
+		fn(child)
+	}
+	for _, child := range p.AllOf {
+		fn(child)
+	}
+	for _, child := range p.AnyOf {
+		fn(child)
+	}
+	for _, child := range p.OneOf {
+		fn(child)
+	}
+	for _, child := range p.Definitions {
+		fn(child)
+	}
+}
+
+// forEach calls fn with p and every subschema below it.
+func (p *property) forEach(fn func(*property)) {
+	fn(p)
+	p.forEachChild(func(child *property) {
+		child.forEach(fn)
+	})
+}
+
+func sortedKeys(m map[string]*property) []string {
+	keys := make([]string, 0, len(m))
+	for name := range m {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
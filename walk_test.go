@@ -0,0 +1,80 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type WalkAddress struct {
+	Street string
+}
+
+type ExampleJSONWalk struct {
+	Name      string
+	Addresses []WalkAddress
+	Home      WalkAddress
+	Point     [2]int `jsonschema:"tuple"`
+}
+
+func TestWalk(t *testing.T) {
+	t.Run("nested struct", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONWalk{})
+
+		var paths []string
+		j.Walk(func(path string, p *property) {
+			paths = append(paths, path)
+		})
+
+		expected := []string{
+			"",
+			"Addresses",
+			"Addresses[]",
+			"Addresses[].Street",
+			"Home",
+			"Home.Street",
+			"Name",
+			"Point",
+			"Point[0]",
+			"Point[1]",
+		}
+		if diff := cmp.Diff(expected, paths); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("definitions", func(t *testing.T) {
+		j := &Document{UseDefinitions: true}
+		j.Read(&ExampleJSONWalk{})
+
+		visited := 0
+		var refs []string
+		j.Walk(func(path string, p *property) {
+			visited++
+			if p.Ref != "" {
+				refs = append(refs, path)
+			}
+		})
+
+		if visited != 10 {
+			t.Errorf("expected 10 visited nodes, got %d", visited)
+		}
+		if diff := cmp.Diff([]string{"Addresses[]", "Home"}, refs); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("changes are applied", func(t *testing.T) {
+		j := &Document{}
+		j.Read(&ExampleJSONWalk{})
+
+		j.Walk(func(path string, p *property) {
+			if p.Type == "string" {
+				p.Comment = "visited " + path
+			}
+		})
+
+		if j.Properties["Home"].Properties["Street"].Comment != "visited Home.Street" {
+			t.Errorf("unexpected comment %q", j.Properties["Home"].Properties["Street"].Comment)
+		}
+	})
+}
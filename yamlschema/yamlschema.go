@@ -0,0 +1,107 @@
+/*
+Package yamlschema reads and writes JSON-Schema Documents as YAML. It is kept
+apart from package jsonschema, so that only programs dealing with YAML depend
+on gopkg.in/yaml.v3.
+*/
+package yamlschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/losisin/go-jsonschema-generator"
+	"gopkg.in/yaml.v3"
+)
+
+// Read infers the schema of d from an example YAML document, such as a
+// sample configuration file. The document is decoded into generic maps and
+// slices which are then read like ReadDeep reads a value, so every mapping
+// becomes an object and every sequence an array typed by its first element.
+func Read(d *jsonschema.Document, data []byte) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	return d.TryReadDeep(normalize(doc))
+}
+
+// normalize converts the mappings decoded by yaml.v3, which use interface{}
+// keys when any key isn't a string, into JSON style map[string]interface{}
+// values.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalize(value)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalize(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalize(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Marshal encodes d as YAML, with the same structure and key order as the
+// JSON output.
+func Marshal(d *jsonschema.Document) ([]byte, error) {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, so decoding it keeps the key order; clearing the
+	// styles turns its flow collections and quoted strings into block YAML.
+	var node yaml.Node
+	if err := yaml.Unmarshal(body, &node); err != nil {
+		return nil, err
+	}
+	clearStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// yaml11Scalar matches the plain scalars that YAML 1.1 resolves to
+// booleans, nulls or numbers, e.g. yes, off, ~ and 0777. yaml.v3 quotes
+// those of YAML 1.2 only, so these strings keep their quotes for tools
+// still reading YAML 1.1.
+var yaml11Scalar = regexp.MustCompile(`^(?:` +
+	`[yYnN]|[Yy]es|YES|[Nn]o|NO|[Tt]rue|TRUE|[Ff]alse|FALSE|[Oo]n|ON|[Oo]ff|OFF|` +
+	`~|[Nn]ull|NULL|` +
+	`[-+]?(?:0b[01_]+|0[0-7_]+|0|[1-9][0-9_]*|0x[0-9a-fA-F_]+|[1-9][0-9_]*(?::[0-5]?[0-9])+)|` +
+	`[-+]?(?:[0-9][0-9_]*)?\.[0-9.]*(?:[eE][-+][0-9]+)?|` +
+	`[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+\.[0-9_]*|` +
+	`[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN)` +
+	`)$`)
+
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str" && yaml11Scalar.MatchString(node.Value) {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
@@ -0,0 +1,137 @@
+package yamlschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/losisin/go-jsonschema-generator"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRead(t *testing.T) {
+	data := []byte(`
+name: api
+replicas: 3
+ratio: 0.5
+debug: false
+started: 2023-01-02T15:04:05Z
+ports:
+  - 80
+  - 443
+database:
+  host: localhost
+  pool:
+    max: 10
+  1: numeric key
+`)
+
+	j := &jsonschema.Document{}
+	if err := Read(j, data); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+		"$schema": "http://json-schema.org/schema#",
+		"type": "object",
+		"properties": {
+			"database": {
+				"type": "object",
+				"properties": {
+					"1": {"type": "string"},
+					"host": {"type": "string"},
+					"pool": {"type": "object", "properties": {"max": {"type": "integer"}}}
+				}
+			},
+			"debug": {"type": "boolean"},
+			"name": {"type": "string"},
+			"ports": {"type": "array", "items": {"type": "integer"}},
+			"ratio": {"type": "number"},
+			"replicas": {"type": "integer"},
+			"started": {"type": "string", "format": "date-time"}
+		}
+	}`
+	if diff := cmp.Diff(decodeJSON(t, []byte(expected)), decodeJSON(t, mustMarshal(t, j))); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	j := &jsonschema.Document{}
+	if err := Read(j, []byte("a: [1, 2")); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	j := &jsonschema.Document{}
+	j.Read(&struct {
+		Name    string `json:"name" jsonschema:"default=true,enum=true|yes|1"`
+		Retries int    `json:"retries,omitempty" jsonschema:"default=3"`
+		Tags    []string
+		Address struct {
+			Street string
+		}
+	}{})
+
+	out, err := Marshal(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), "$schema: http://json-schema.org/schema#\ntype: object\n") {
+		t.Errorf("expected block YAML in JSON key order, got:\n%s", out)
+	}
+
+	var fromYAML interface{}
+	if err := yaml.Unmarshal(out, &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	reencoded, err := json.Marshal(fromYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(decodeJSON(t, mustMarshal(t, j)), decodeJSON(t, reencoded)); diff != "" {
+		t.Errorf("YAML doesn't round-trip:\n%s", diff)
+	}
+}
+
+func TestMarshalYAML11Scalars(t *testing.T) {
+	j := &jsonschema.Document{}
+	j.Read(&struct {
+		Answer string `json:"answer" jsonschema:"enum=yes|no|on|off|y|~|0777|1:30|plain"`
+	}{})
+
+	out, err := Marshal(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, quoted := range []string{`"yes"`, `"no"`, `"on"`, `"off"`, `"y"`, `"~"`, `"0777"`, `"1:30"`} {
+		if !strings.Contains(string(out), "- "+quoted+"\n") {
+			t.Errorf("expected %s to be quoted, got:\n%s", quoted, out)
+		}
+	}
+	if !strings.Contains(string(out), "- plain\n") {
+		t.Errorf("expected plain to be left unquoted, got:\n%s", out)
+	}
+}
+
+func mustMarshal(t *testing.T, d *jsonschema.Document) []byte {
+	t.Helper()
+	body, err := d.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body
+}
+
+func decodeJSON(t *testing.T, data []byte) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}